@@ -0,0 +1,235 @@
+// gqldoc 提取 go 文档注释，生成注册描述信息的代码
+// 生成的代码在 init 中调用 gqlh.RegisterDocs，结构、字段、函数没有指定描述信息时使用文档注释
+//
+// 用法：在 Query、Mutation 函数和结构所在的包中加入
+//
+//	//go:generate go run github.com/seerx/gql/cmd/gqldoc
+//
+// 然后执行 go generate，生成 gql_docs.go 文件
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const header = "// Code generated by gqldoc. DO NOT EDIT.\n\n"
+
+func main() {
+	output := flag.String("o", "gql_docs.go", "生成的文件名称")
+	pkgPath := flag.String("pkg", "", "包的导入路径，默认根据 go.mod 计算")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, *output, *pkgPath); err != nil {
+		fmt.Fprintln(os.Stderr, "gqldoc:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output, pkgPath string) error {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		name := info.Name()
+		return !strings.HasSuffix(name, "_test.go") && name != filepath.Base(output)
+	}, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) != 1 {
+		return fmt.Errorf("目录 %s 中必须有且只有一个包", dir)
+	}
+
+	for name, pkg := range pkgs {
+		if pkgPath == "" {
+			if pkgPath, err = importPath(dir, name); err != nil {
+				return err
+			}
+		}
+		docs := collect(pkg, pkgPath)
+		src, err := generate(name, docs)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dir, output), src, 0644)
+	}
+	return nil
+}
+
+// importPath 根据 go.mod 计算包的导入路径，main 包的路径为 main
+func importPath(dir, pkgName string) (string, error) {
+	if pkgName == "main" {
+		return "main", nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; {
+		if module := moduleName(filepath.Join(root, "go.mod")); module != "" {
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", err
+			}
+			if rel == "." {
+				return module, nil
+			}
+			return module + "/" + filepath.ToSlash(rel), nil
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", fmt.Errorf("找不到 go.mod，请使用 -pkg 指定包的导入路径")
+		}
+		root = parent
+	}
+}
+
+// moduleName 读取 go.mod 中的模块名称
+func moduleName(file string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(line[len("module "):]), "\"")
+		}
+	}
+	return ""
+}
+
+// collect 收集结构、结构字段、函数和方法的文档注释
+func collect(pkg *ast.Package, pkgPath string) map[string]string {
+	docs := map[string]string{}
+	add := func(key, name string, groups ...*ast.CommentGroup) {
+		for _, g := range groups {
+			if text := commentText(g, name); text != "" {
+				docs[pkgPath+"."+key] = text
+				return
+			}
+		}
+	}
+
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					add(d.Name.Name, d.Name.Name, d.Doc)
+				} else if recv := receiverName(d.Recv); recv != "" {
+					add(recv+"."+d.Name.Name, d.Name.Name, d.Doc)
+				}
+			case *ast.GenDecl:
+				if d.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range d.Specs {
+					ts := spec.(*ast.TypeSpec)
+					if len(d.Specs) == 1 {
+						add(ts.Name.Name, ts.Name.Name, ts.Doc, d.Doc)
+					} else {
+						add(ts.Name.Name, ts.Name.Name, ts.Doc, ts.Comment)
+					}
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					for _, field := range st.Fields.List {
+						for _, name := range fieldNames(field) {
+							add(ts.Name.Name+"."+name, name, field.Doc, field.Comment)
+						}
+					}
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// receiverName 方法接收者的类型名称
+func receiverName(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	typ := recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// fieldNames 结构字段的名称，嵌入字段使用类型名称
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) > 0 {
+		var names []string
+		for _, n := range field.Names {
+			names = append(names, n.Name)
+		}
+		return names
+	}
+	typ := field.Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return []string{t.Name}
+	case *ast.SelectorExpr:
+		return []string{t.Sel.Name}
+	}
+	return nil
+}
+
+// commentText 注释文本，去掉开头的名称，如 "Goods 商品信息" 为 "商品信息"
+func commentText(g *ast.CommentGroup, name string) string {
+	if g == nil {
+		return ""
+	}
+	text := strings.TrimSpace(g.Text())
+	if strings.HasPrefix(text, name+" ") {
+		text = strings.TrimSpace(text[len(name)+1:])
+	}
+	return text
+}
+
+// generate 生成代码
+func generate(pkgName string, docs map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	buf.WriteString("import \"github.com/seerx/gql/pkg/gqlh\"\n\n")
+	buf.WriteString("func init() {\n\tgqlh.RegisterDocs(map[string]string{\n")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "\t\t%s: %s,\n", strconv.Quote(key), strconv.Quote(docs[key]))
+	}
+	buf.WriteString("\t})\n}\n")
+	return format.Source(buf.Bytes())
+}
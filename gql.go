@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/seerx/gql/pkg/def"
 
@@ -24,19 +25,41 @@ type GQL struct {
 
 	queryManager          *gqlh.ResolverManager
 	mutationManager       *gqlh.ResolverManager
+	subscriptionManager   *gqlh.ResolverManager
+	fieldManager          *gqlh.ResolverManager
 	responseObjectManager *gqlh.ResponseObjectManager
 	requestObjectManager  *gqlh.RequestObjectManager
+	typeManager           *gqlh.TypeManager
 	// 注入对象结构
 	inject *gqlh.Inject
+	// WebSocket 订阅检查请求来源
+	checkOrigin func(r *http.Request) bool
+	// WebSocket 和 SSE 订阅的心跳间隔
+	keepAlive time.Duration
 	// 准备要注册的函数
-	tobeInject    []interface{}    // 注入函数
-	tobeQueries   []*resolveWraper // 查询函数
-	tobeMutations []*resolveWraper // 操作函数
+	tobeEnums         []map[string]interface{} // 枚举定义
+	tobeScalars       []*scalarWraper          // 自定义标量定义
+	tobeAbstracts     []*abstractWraper        // 接口定义
+	tobeInject        []interface{}            // 注入函数
+	tobeQueries       []*resolveWraper         // 查询函数
+	tobeMutations     []*resolveWraper         // 操作函数
+	tobeSubscriptions []*resolveWraper         // 订阅函数
+}
+
+type abstractWraper struct {
+	iface   interface{}
+	impls   []interface{}
+	isUnion bool
+}
+
+type scalarWraper struct {
+	sample interface{}
+	cfg    *gqlh.ScalarConfig
 }
 
 type resolveWraper struct {
-	function     interface{}
-	inputCheckFn gqlh.ValidatorFn
+	function interface{}
+	options  *gqlh.ResolverOptions
 }
 
 var instance *GQL
@@ -48,14 +71,20 @@ func init() {
 // NewGQL 创建 GQL 实例
 func NewGQL() *GQL {
 	inject := gqlh.NewInject()
-	resObj := gqlh.NewResponseObjectManager()
-	reqObj := gqlh.NewRequestObjectManager()
+	typeManager := gqlh.NewTypeManager()
+	resObj := gqlh.NewResponseObjectManager(typeManager)
+	reqObj := gqlh.NewRequestObjectManager(typeManager)
+	fieldManager := gqlh.NewFieldResolverManager(inject, resObj, reqObj)
+	resObj.SetFieldResolverManager(fieldManager)
 	return &GQL{
 		handlerConfig:         new(handler.Config),
 		responseObjectManager: resObj, //  gqlh.NewResponseObjectManager(),
 		requestObjectManager:  reqObj, // gqlh.NewRequestObjectManager(),
+		typeManager:           typeManager,
 		queryManager:          gqlh.NewQueryResolverManager(inject, resObj, reqObj),
 		mutationManager:       gqlh.NewMutationResolverManager(inject, resObj, reqObj),
+		subscriptionManager:   gqlh.NewSubscriptionResolverManager(inject, resObj, reqObj),
+		fieldManager:          fieldManager,
 		inject:                inject, //   gqlh.NewInject(),
 	}
 }
@@ -66,6 +95,7 @@ func Get() *GQL {
 }
 
 // NewHandler 设置 json 是否格式化
+// cfg.FormatErrorFn 为 nil 时使用 gqlh.FormatError，错误信息的 extensions 中包含 code
 func (g *GQL) NewHandler(cfg *handler.Config) http.Handler {
 	s, err := g.GetSchema()
 	if err != nil {
@@ -73,13 +103,18 @@ func (g *GQL) NewHandler(cfg *handler.Config) http.Handler {
 	}
 
 	g.customConfig = cfg
+	formatErrorFn := cfg.FormatErrorFn
+	if formatErrorFn == nil {
+		// 错误信息的 extensions 中包含 code
+		formatErrorFn = gqlh.FormatError
+	}
 	g.handlerConfig = &handler.Config{
 		Schema:           s,
 		Pretty:           cfg.Pretty,
 		Playground:       cfg.Playground,
 		GraphiQL:         cfg.GraphiQL,
 		ResultCallbackFn: cfg.ResultCallbackFn,
-		FormatErrorFn:    cfg.FormatErrorFn,
+		FormatErrorFn:    formatErrorFn,
 		RootObjectFn: func(ctx context.Context, r *http.Request) map[string]interface{} {
 			var root map[string]interface{}
 			if cfg.RootObjectFn != nil {
@@ -94,7 +129,74 @@ func (g *GQL) NewHandler(cfg *handler.Config) http.Handler {
 		},
 	}
 
-	return handler.New(g.handlerConfig)
+	h := handler.New(g.handlerConfig)
+	ws := g.NewWebSocketHandler()
+	sse := g.NewSSEHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gqlh.IsWebSocketUpgrade(r) {
+			// WebSocket 订阅
+			ws.ServeHTTP(w, r)
+			return
+		}
+		if gqlh.IsEventStreamRequest(r) {
+			// SSE 订阅
+			sse.ServeHTTP(w, r)
+			return
+		}
+		// 保存原始的 variables，用于区分提交的 null 和未提交的参数
+		h.ServeHTTP(w, gqlh.CaptureVariables(r))
+	})
+}
+
+// subscriptionConfig 订阅处理器的配置，与 NewHandler 使用相同的 RootObjectFn 和 FormatErrorFn
+func (g *GQL) subscriptionConfig() *gqlh.SubscriptionConfig {
+	s, err := g.GetSchema()
+	if err != nil {
+		panic(err)
+	}
+	rootObjectFn := g.handlerConfig.RootObjectFn
+	if rootObjectFn == nil {
+		// 没有调用 NewHandler
+		rootObjectFn = func(ctx context.Context, r *http.Request) map[string]interface{} {
+			root := make(map[string]interface{})
+			g.inject.StoreContext(ctx, r, root)
+			return root
+		}
+	}
+	formatErrorFn := g.handlerConfig.FormatErrorFn
+	if formatErrorFn == nil {
+		formatErrorFn = gqlh.FormatError
+	}
+	return &gqlh.SubscriptionConfig{
+		Schema:                s,
+		RootObjectFn:          rootObjectFn,
+		FormatErrorFn:         formatErrorFn,
+		KeepAlive:             g.keepAlive,
+		ResponseObjectManager: g.responseObjectManager,
+	}
+}
+
+// NewWebSocketHandler 创建 WebSocket 订阅处理器，支持 graphql-ws 和 graphql-transport-ws 协议
+// NewHandler 返回的 handler 已经处理 WebSocket 连接请求，单独使用时应在 NewHandler 之后调用
+func (g *GQL) NewWebSocketHandler() http.Handler {
+	return gqlh.NewWebSocketHandler(g.subscriptionConfig(), g.checkOrigin)
+}
+
+// NewSSEHandler 创建 SSE 订阅处理器（GraphQL over SSE 协议的 distinct connections 模式）
+// 用于无法使用 WebSocket 的客户端；NewHandler 返回的 handler 已经处理 Accept 为 text/event-stream 的请求，
+// 单独使用时应在 NewHandler 之后调用
+func (g *GQL) NewSSEHandler() http.Handler {
+	return gqlh.NewSSEHandler(g.subscriptionConfig())
+}
+
+// SetSubscriptionKeepAlive 设置 WebSocket 和 SSE 订阅的心跳间隔，默认为 gqlh.DefaultKeepAlive
+func (g *GQL) SetSubscriptionKeepAlive(keepAlive time.Duration) {
+	g.keepAlive = keepAlive
+}
+
+// SetWebSocketCheckOrigin 设置 WebSocket 订阅检查请求来源的函数，默认只允许同源请求
+func (g *GQL) SetWebSocketCheckOrigin(checkOrigin func(r *http.Request) bool) {
+	g.checkOrigin = checkOrigin
 }
 
 // GetSchema 获取 GraphQL 结构，如果哈没有创建，则创建
@@ -103,6 +205,18 @@ func (g *GQL) GetSchema() (*graphql.Schema, error) {
 		return g.schema, nil
 	}
 
+	// 注册枚举
+	for _, values := range g.tobeEnums {
+		g.typeManager.RegisterEnum(values)
+	}
+	// 注册自定义标量
+	for _, sc := range g.tobeScalars {
+		g.typeManager.RegisterScalar(sc.sample, sc.cfg)
+	}
+	// 注册接口
+	for _, abs := range g.tobeAbstracts {
+		g.typeManager.RegisterAbstract(abs.iface, abs.impls, abs.isUnion)
+	}
 	// 注册注入函数
 	for _, fn := range g.tobeInject {
 		g.inject.Inject(fn)
@@ -110,19 +224,29 @@ func (g *GQL) GetSchema() (*graphql.Schema, error) {
 	// 注册查询函数
 	for _, obj := range g.tobeQueries {
 		// g.doRegisterQuery(obj)
-		g.doRegisterResolver(g.queryManager, obj.function, obj.inputCheckFn)
+		g.doRegisterResolver(g.queryManager, obj.function, obj.options)
 	}
 	// 注册操作函数
 	for _, obj := range g.tobeMutations {
 		// g.doRegisterMutation(obj)
-		g.doRegisterResolver(g.mutationManager, obj.function, obj.inputCheckFn)
+		g.doRegisterResolver(g.mutationManager, obj.function, obj.options)
+	}
+	// 注册订阅函数
+	for _, obj := range g.tobeSubscriptions {
+		g.doRegisterResolver(g.subscriptionManager, obj.function, obj.options)
 	}
 
 	// 生成 graphql 结构
+	query := g.queryManager.CreateResolveObject()
+	mutation := g.mutationManager.CreateResolveObject()
+	subscription := g.subscriptionManager.CreateResolveObject()
 	schema, err := graphql.NewSchema(
 		graphql.SchemaConfig{
-			Query:    g.queryManager.CreateResolveObject(),
-			Mutation: g.mutationManager.CreateResolveObject(),
+			Query:        query,
+			Mutation:     mutation,
+			Subscription: subscription,
+			// 接口的实现对象可能不会被直接引用，需要加入类型列表
+			Types: g.responseObjectManager.Types(),
 		},
 	)
 	if err == nil {
@@ -134,7 +258,11 @@ func (g *GQL) GetSchema() (*graphql.Schema, error) {
 func (g *GQL) createSummary(filter func(info *gqlh.RegisterInfo) bool) string {
 	query := "Query:"
 	mutation := "\nMutation:"
-	for _, info := range g.registerInfos {
+	subscription := "\nSubscription:"
+	field := "\nField:"
+	// 结构字段方法在生成 schema 时注册
+	infos := append(g.registerInfos[:len(g.registerInfos):len(g.registerInfos)], g.fieldManager.Infos()...)
+	for _, info := range infos {
 		if filter != nil && !filter(info) {
 			continue
 		}
@@ -144,18 +272,36 @@ func (g *GQL) createSummary(filter func(info *gqlh.RegisterInfo) bool) string {
 		} else {
 			str += info.Func + "@" + info.Package
 		}
+		if info.Name != info.Func {
+			// 与函数名称不同
+			str += " -> " + info.Name
+		}
 		if info.Error == "" {
 			str += "\t[OK]"
 		} else {
 			str += "\t[" + info.Error + "]"
 		}
-		if info.Type == "Query" {
+		switch info.Type {
+		case "Query":
 			query += str
-		} else {
+		case "Subscription":
+			subscription += str
+		case "Field":
+			field += str
+		default:
 			mutation += str
 		}
 	}
-	return query + mutation + "\n"
+	conflicts := g.typeManager.Conflicts()
+	if len(conflicts) == 0 {
+		return query + mutation + subscription + field + "\n"
+	}
+	// 名称冲突的类型
+	types := "\nType Conflicts:"
+	for _, c := range conflicts {
+		types += "\n\t" + c.String()
+	}
+	return query + mutation + subscription + field + types + "\n"
 }
 
 // Summary 注册说明
@@ -182,9 +328,17 @@ func (g *GQL) RegisterMutation(mutation interface{}) {
 
 // RegisterMutationWithValidateFn 注册操作，并提供输入参数验证函数
 func (g *GQL) RegisterMutationWithValidateFn(mutation interface{}, validateFn gqlh.ValidatorFn) {
+	g.RegisterMutationWithOptions(mutation, &gqlh.ResolverOptions{
+		ValidateFn: validateFn,
+	})
+}
+
+// RegisterMutationWithOptions 注册操作，并提供注册选项
+// @param mutation 可以是一个函数，也可以是一个有多个函数的结构体
+func (g *GQL) RegisterMutationWithOptions(mutation interface{}, opts *gqlh.ResolverOptions) {
 	g.tobeMutations = append(g.tobeMutations, &resolveWraper{
-		function:     mutation,
-		inputCheckFn: validateFn,
+		function: mutation,
+		options:  opts,
 	})
 }
 
@@ -197,13 +351,38 @@ func (g *GQL) RegisterQuery(query interface{}) {
 // RegisterQueryWithValidateFn 注册查询，并提供一个输入参数验证函数
 // @param query 可以是一个函数，也可以是一个有多个函数的结构体
 func (g *GQL) RegisterQueryWithValidateFn(query interface{}, validateFn gqlh.ValidatorFn) {
+	g.RegisterQueryWithOptions(query, &gqlh.ResolverOptions{
+		ValidateFn: validateFn,
+	})
+}
+
+// RegisterQueryWithOptions 注册查询，并提供注册选项
+// @param query 可以是一个函数，也可以是一个有多个函数的结构体
+func (g *GQL) RegisterQueryWithOptions(query interface{}, opts *gqlh.ResolverOptions) {
 	g.tobeQueries = append(g.tobeQueries, &resolveWraper{
-		function:     query,
-		inputCheckFn: validateFn,
+		function: query,
+		options:  opts,
 	})
 }
 
-func (g *GQL) doRegisterResolver(manager *gqlh.ResolverManager, resolveFunc interface{}, validateFn gqlh.ValidatorFn) {
+// RegisterSubscription 注册订阅，加入到待注册列表
+// 订阅函数的参数与查询函数相同，返回值必须是 (<-chan T, error)，每从 channel 中收到一个值推送一次结果
+// channel 关闭时订阅结束；客户端断开时，函数接收的 context.Context 被取消，函数应停止发送并关闭 channel
+// @param subscription 可以是一个函数，也可以是一个有多个函数的结构体
+func (g *GQL) RegisterSubscription(subscription interface{}) {
+	g.RegisterSubscriptionWithOptions(subscription, nil)
+}
+
+// RegisterSubscriptionWithOptions 注册订阅，并提供注册选项
+// @param subscription 可以是一个函数，也可以是一个有多个函数的结构体
+func (g *GQL) RegisterSubscriptionWithOptions(subscription interface{}, opts *gqlh.ResolverOptions) {
+	g.tobeSubscriptions = append(g.tobeSubscriptions, &resolveWraper{
+		function: subscription,
+		options:  opts,
+	})
+}
+
+func (g *GQL) doRegisterResolver(manager *gqlh.ResolverManager, resolveFunc interface{}, opts *gqlh.ResolverOptions) {
 	funcType := reflect.TypeOf(resolveFunc)
 	kind := funcType.Kind()
 	if kind == reflect.Func {
@@ -219,7 +398,7 @@ func (g *GQL) doRegisterResolver(manager *gqlh.ResolverManager, resolveFunc inte
 			Struct: nil,
 		}
 
-		info := manager.RegisterResolver(fn, validateFn)
+		info := manager.RegisterResolver(fn, opts)
 		g.registerInfos = append(g.registerInfos, info)
 	} else if kind == reflect.Struct {
 		// 是一个结构，遍历其所有函数
@@ -235,7 +414,7 @@ func (g *GQL) doRegisterResolver(manager *gqlh.ResolverManager, resolveFunc inte
 				Func:   method.Func,
 				Struct: resolveFunc,
 			}
-			info := manager.RegisterResolver(fn, validateFn)
+			info := manager.RegisterResolver(fn, opts)
 			g.registerInfos = append(g.registerInfos, info)
 		}
 	}
@@ -247,3 +426,81 @@ func (g *GQL) doRegisterResolver(manager *gqlh.ResolverManager, resolveFunc inte
 func (g *GQL) RegisterInject(injectFn interface{}) {
 	g.tobeInject = append(g.tobeInject, injectFn)
 }
+
+// RegisterEnum 注册枚举类型,只是加入到待注册列表
+// @param values 枚举值名称与 go 常量的对应关系，所有常量必须是同一个自定义的 string 或整数类型
+// 例如 g.RegisterEnum(map[string]interface{}{"PAID": OrderPaid, "SHIPPED": OrderShipped})
+func (g *GQL) RegisterEnum(values map[string]interface{}) {
+	g.tobeEnums = append(g.tobeEnums, values)
+}
+
+// RegisterInterface 注册接口类型,只是加入到待注册列表
+// 返回该接口的函数将生成 graphql interface，接口的字段为所有实现结构共有的字段
+// @param iface 接口的空指针，例如 (*Searchable)(nil)
+// @param impls 实现接口的结构（或结构指针）
+func (g *GQL) RegisterInterface(iface interface{}, impls ...interface{}) {
+	g.tobeAbstracts = append(g.tobeAbstracts, &abstractWraper{
+		iface: iface,
+		impls: impls,
+	})
+}
+
+// RegisterUnion 注册接口类型,只是加入到待注册列表
+// 返回该接口的函数将生成 graphql union
+// @param iface 接口的空指针，例如 (*SearchResult)(nil)
+// @param impls 实现接口的结构（或结构指针）
+func (g *GQL) RegisterUnion(iface interface{}, impls ...interface{}) {
+	g.tobeAbstracts = append(g.tobeAbstracts, &abstractWraper{
+		iface:   iface,
+		impls:   impls,
+		isUnion: true,
+	})
+}
+
+// SetTypeNaming 设置结构、枚举等生成 graphql 类型时的命名策略，默认使用 go 类型名称
+// 不同包中的同名类型，按函数的注册顺序，后出现的类型自动使用包名加类型名称命名，可以通过 Summary 查看
+// 结构也可以通过 GraphQLName 方法或者 _ struct{} `gql:"typename=ShopGoods"` 标记指定名称
+func (g *GQL) SetTypeNaming(naming gqlh.TypeNaming) {
+	g.typeManager.SetNaming(naming)
+}
+
+// OnDeprecatedUsage 设置使用已废弃字段时的回调函数
+// 字段通过 gql:"deprecated=原因" 标记废弃，Query 和 Mutation 通过注册选项 DeprecationReason 废弃
+// 同一个请求中每个字段只回调一次，可以用于统计仍在使用这些字段的客户端
+func (g *GQL) OnDeprecatedUsage(fn gqlh.DeprecatedUsageFn) {
+	g.responseObjectManager.SetDeprecatedUsageFn(fn)
+}
+
+// Use 添加 resolver 中间件，包装每一次 Query、Mutation、Subscription 函数和结构字段方法的调用
+// 中间件可以获取注册信息、调用类型和参数，可以直接返回（不执行函数），也可以修改函数返回的结果和错误
+// 先添加的中间件在外层，必须在 NewHandler 或 GetSchema 之前调用
+func (g *GQL) Use(middlewares ...gqlh.Middleware) {
+	g.queryManager.Use(middlewares...)
+	g.mutationManager.Use(middlewares...)
+	g.subscriptionManager.Use(middlewares...)
+	g.fieldManager.Use(middlewares...)
+}
+
+// SetPanicLogger 设置记录未知 panic 的函数，默认使用 log 输出错误和调用栈
+// Query、Mutation、Subscription 函数、结构字段方法和注入函数中的 panic 都会转换为错误返回，不会导致请求失败：
+// 参数验证失败返回 VALIDATION_FAILED，注入函数以 error panic 返回 INJECT_FAILED，其他 panic 返回 INTERNAL_SERVER_ERROR 并记录日志
+func (g *GQL) SetPanicLogger(logger gqlh.PanicLogger) {
+	g.responseObjectManager.SetPanicLogger(logger)
+}
+
+// SetFieldNaming 设置字段命名策略，默认保持 go 的名称
+// 作用于结构字段（json tag 中指定了名称的除外）、输入参数字段、Query 和 Mutation 函数以及结构字段方法
+func (g *GQL) SetFieldNaming(naming gqlh.FieldNaming) {
+	g.typeManager.SetFieldNaming(naming)
+}
+
+// RegisterScalar 注册自定义标量类型,只是加入到待注册列表
+// 该类型的字段、参数和返回值都将使用此标量，例如 g.RegisterScalar([]byte{}, gqlh.Base64Scalar)
+// @param sample 该类型的一个值，例如 decimal.Decimal{}
+// @param cfg 标量定义，提供名称以及 Serialize、ParseValue 函数
+func (g *GQL) RegisterScalar(sample interface{}, cfg *gqlh.ScalarConfig) {
+	g.tobeScalars = append(g.tobeScalars, &scalarWraper{
+		sample: sample,
+		cfg:    cfg,
+	})
+}
@@ -13,6 +13,8 @@ type FuncInfo struct {
 	Func   reflect.Value // 函数
 	Type   reflect.Type  // 函数类型
 	Struct interface{}   // 函数所属结构,可能是 nil
+	// 结构字段方法的接收者类型，不为 nil 时第一个参数来自 graphql 的 Source
+	Receiver reflect.Type
 }
 
 // GetStructName 获取函数所在结构的名称
@@ -21,6 +23,10 @@ func (fi *FuncInfo) GetStructName() string {
 		structType := reflect.TypeOf(fi.Struct)
 		return structType.Name()
 	}
+	if fi.Receiver != nil {
+		// 结构字段方法
+		return fi.Receiver.Elem().Name()
+	}
 
 	return ""
 }
@@ -28,9 +34,18 @@ func (fi *FuncInfo) GetStructName() string {
 // GetDescribe 获取函数的描述信息
 // 函数名称以 Desc 结尾的，多时描述信息提供函数
 func (fi *FuncInfo) GetDescribe() string {
+	var structType reflect.Type
+	var structValue reflect.Value
 	if fi.Struct != nil {
+		structType = reflect.TypeOf(fi.Struct)
+		structValue = reflect.ValueOf(fi.Struct)
+	} else if fi.Receiver != nil {
+		// 字段方法，使用接收者类型的零值调用
+		structType = fi.Receiver
+		structValue = reflect.New(fi.Receiver.Elem())
+	}
+	if structType != nil {
 		descFnName := fi.Name + "Desc"
-		structType := reflect.TypeOf(fi.Struct)
 		method, ok := structType.MethodByName(descFnName)
 		if ok {
 			tp := method.Type
@@ -39,7 +54,7 @@ func (fi *FuncInfo) GetDescribe() string {
 				op := tp.Out(0)
 				if utils.IsStringType(op.Kind()) {
 					// 输出参数是 string 类型
-					res := method.Func.Call([]reflect.Value{reflect.ValueOf(fi.Struct)})
+					res := method.Func.Call([]reflect.Value{structValue})
 					v, o := res[0].Interface().(string)
 					if o {
 						return v
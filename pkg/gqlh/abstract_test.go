@@ -0,0 +1,89 @@
+package gqlh_test
+
+import (
+	"testing"
+
+	"github.com/seerx/gql"
+)
+
+// Animal 生成 graphql interface，字段为所有实现结构共有的字段 Name
+type Animal interface {
+	Sound() string
+}
+
+// Pet 生成 graphql union
+type Pet interface {
+	Owner() string
+}
+
+type Dog struct {
+	Name  string
+	Barks int
+}
+
+func (*Dog) Sound() string { return "woof" }
+func (*Dog) Owner() string { return "" }
+
+type Cat struct {
+	Name  string
+	Lives int
+}
+
+func (*Cat) Sound() string { return "meow" }
+func (*Cat) Owner() string { return "" }
+
+type Zoo struct {
+	Best    Animal
+	Animals []Animal
+	Pets    []Pet
+}
+
+func GetZoo() (*Zoo, error) {
+	dog, cat := &Dog{Name: "d", Barks: 3}, &Cat{Name: "c", Lives: 9}
+	return &Zoo{Best: cat, Animals: []Animal{dog, cat}, Pets: []Pet{cat, dog}}, nil
+}
+
+func TestInterfaceAndUnion(t *testing.T) {
+	g := gql.NewGQL()
+	g.RegisterInterface((*Animal)(nil), Dog{}, Cat{})
+	g.RegisterUnion((*Pet)(nil), &Dog{}, &Cat{})
+	g.RegisterQuery(GetZoo)
+	h := newHandler(t, g)
+
+	res := post(t, h, `{GetZoo {
+		Best {__typename Name ... on Cat {Lives}}
+		Animals {__typename Name ... on Dog {Barks}}
+		Pets {__typename ... on Dog {Barks} ... on Cat {Lives}}
+	}}`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors[0].Message)
+	}
+	zoo := path(res.Data, "GetZoo")
+	if best := path(zoo, "Best"); path(best, "__typename") != "Cat" || path(best, "Lives") != 9.0 {
+		t.Errorf("Best 为 %v", best)
+	}
+	animals, _ := path(zoo, "Animals").([]interface{})
+	if len(animals) != 2 || path(animals[0], "__typename") != "Dog" || path(animals[0], "Barks") != 3.0 ||
+		path(animals[1], "Name") != "c" {
+		t.Errorf("Animals 为 %v", animals)
+	}
+	pets, _ := path(zoo, "Pets").([]interface{})
+	if len(pets) != 2 || path(pets[0], "Lives") != 9.0 || path(pets[1], "Barks") != 3.0 {
+		t.Errorf("Pets 为 %v", pets)
+	}
+
+	// interface 只有共有的字段，union 没有字段
+	res = post(t, h, `{__type(name: "Animal") {kind fields {name} possibleTypes {name}}}`, nil)
+	iface := path(res.Data, "__type")
+	if path(iface, "kind") != "INTERFACE" {
+		t.Fatalf("Animal 为 %v", iface)
+	}
+	if fields, _ := path(iface, "fields").([]interface{}); len(fields) != 1 || path(fields[0], "name") != "Name" {
+		t.Errorf("Animal 的字段为 %v", fields)
+	}
+	res = post(t, h, `{__type(name: "Pet") {kind possibleTypes {name}}}`, nil)
+	union := path(res.Data, "__type")
+	if types, _ := path(union, "possibleTypes").([]interface{}); path(union, "kind") != "UNION" || len(types) != 2 {
+		t.Errorf("Pet 为 %v", union)
+	}
+}
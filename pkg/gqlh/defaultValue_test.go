@@ -0,0 +1,111 @@
+package gqlh_test
+
+import (
+	"testing"
+
+	"github.com/seerx/gql"
+)
+
+type OrderStatus string
+
+const (
+	OrderPaid    OrderStatus = "paid"
+	OrderShipped OrderStatus = "shipped"
+)
+
+type OrderFilter struct {
+	Status OrderStatus `gql:"default=PAID"`
+	Limit  int         `gql:"default=20"`
+	Tag    string      `gql:"default='a,b'"`
+}
+
+type FlatOrderFilter struct {
+	_      struct{}    `gql:"flatten"`
+	Status OrderStatus `gql:"default=SHIPPED"`
+}
+
+type OrderList struct {
+	Status OrderStatus
+	Limit  int
+	Tag    string
+}
+
+func Orders(f *OrderFilter) (*OrderList, error) {
+	return &OrderList{Status: f.Status, Limit: f.Limit, Tag: f.Tag}, nil
+}
+
+func FlatOrders(f *FlatOrderFilter) (*OrderList, error) {
+	return &OrderList{Status: f.Status}, nil
+}
+
+func newOrderGQL() *gql.GQL {
+	g := gql.NewGQL()
+	g.RegisterEnum(map[string]interface{}{"PAID": OrderPaid, "SHIPPED": OrderShipped})
+	g.RegisterQuery(Orders)
+	g.RegisterQuery(FlatOrders)
+	return g
+}
+
+func TestDefaultValueIntrospection(t *testing.T) {
+	h := newHandler(t, newOrderGQL())
+
+	res := post(t, h, `{__type(name: "inputOrderFilter") {inputFields {name defaultValue}}}`, nil)
+	defaults := map[string]interface{}{}
+	fields, _ := path(res.Data, "__type", "inputFields").([]interface{})
+	for _, f := range fields {
+		defaults[path(f, "name").(string)] = path(f, "defaultValue")
+	}
+	want := map[string]interface{}{
+		"Status": "PAID",
+		"Limit":  "20",
+		"Tag":    `"a,b"`,
+	}
+	for name, v := range want {
+		if defaults[name] != v {
+			t.Errorf("%s 的默认值为 %v，期望 %v", name, defaults[name], v)
+		}
+	}
+
+	res = post(t, h, `{__type(name: "Query") {fields {name args {name defaultValue}}}}`, nil)
+	fields, _ = path(res.Data, "__type", "fields").([]interface{})
+	found := false
+	for _, f := range fields {
+		if path(f, "name") != "FlatOrders" {
+			continue
+		}
+		for _, arg := range path(f, "args").([]interface{}) {
+			if path(arg, "name") == "Status" {
+				found = true
+				if v := path(arg, "defaultValue"); v != "SHIPPED" {
+					t.Errorf("参数 Status 的默认值为 %v，期望 SHIPPED", v)
+				}
+			}
+		}
+	}
+	if !found {
+		t.Error("FlatOrders 没有参数 Status")
+	}
+}
+
+func TestDefaultValue(t *testing.T) {
+	h := newHandler(t, newOrderGQL())
+
+	res := post(t, h, `{Orders(OrderFilter: {}) {Status Limit Tag}}`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors[0].Message)
+	}
+	orders := path(res.Data, "Orders")
+	if path(orders, "Status") != "PAID" || path(orders, "Limit") != 20.0 || path(orders, "Tag") != "a,b" {
+		t.Errorf("使用默认值的结果为 %v", orders)
+	}
+
+	res = post(t, h, `{Orders(OrderFilter: {Status: SHIPPED, Limit: 5}) {Status Limit}}`, nil)
+	if orders := path(res.Data, "Orders"); path(orders, "Status") != "SHIPPED" || path(orders, "Limit") != 5.0 {
+		t.Errorf("提交参数的结果为 %v", orders)
+	}
+
+	res = post(t, h, `{FlatOrders {Status}}`, nil)
+	if v := path(res.Data, "FlatOrders", "Status"); v != "SHIPPED" {
+		t.Errorf("平铺参数使用默认值的结果为 %v", v)
+	}
+}
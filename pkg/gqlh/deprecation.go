@@ -0,0 +1,82 @@
+package gqlh
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+// DeprecatedUsage 请求中使用的已废弃字段
+type DeprecatedUsage struct {
+	Type   string // 字段所在的对象，如 Query、Goods
+	Field  string // 字段名称
+	Reason string // 废弃原因
+}
+
+// DeprecatedUsageFn 请求中使用了已废弃的字段（包括 Query 和 Mutation）时调用，
+// 同一个请求中每个字段只调用一次，可以用于统计仍在使用这些字段的客户端
+// r 在没有通过 http 请求执行时为 nil
+type DeprecatedUsageFn func(ctx context.Context, r *http.Request, usage *DeprecatedUsage)
+
+const keyOfDeprecated = "deprecated"
+
+// deprecatedUsed 记录请求中已经报告过的废弃字段
+type deprecatedUsed struct {
+	sync.Mutex
+	fields map[string]bool
+}
+
+// SetDeprecatedUsageFn 设置使用已废弃字段时的回调函数
+func (objm *ResponseObjectManager) SetDeprecatedUsageFn(fn DeprecatedUsageFn) {
+	objm.deprecatedUsageFn = fn
+}
+
+// resolveDeprecated 包装已废弃字段的 resolve 函数，在请求中第一次使用时调用回调函数
+// @param resolve 原来的 resolve 函数，为 nil 时使用 graphql 默认的函数
+func (objm *ResponseObjectManager) resolveDeprecated(reason string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	if resolve == nil {
+		resolve = graphql.DefaultResolveFn
+	}
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if objm.deprecatedUsageFn != nil {
+			objm.reportDeprecated(p, reason)
+		}
+		return resolve(p)
+	}
+}
+
+func (objm *ResponseObjectManager) reportDeprecated(p graphql.ResolveParams, reason string) {
+	usage := &DeprecatedUsage{
+		Field:  p.Info.FieldName,
+		Reason: reason,
+	}
+	if p.Info.ParentType != nil {
+		usage.Type = p.Info.ParentType.Name()
+	}
+
+	var r *http.Request
+	if root, ok := p.Info.RootValue.(map[string]interface{}); ok {
+		r, _ = root[keyOfRequest].(*http.Request)
+		used, ok := root[keyOfDeprecated].(*deprecatedUsed)
+		if !ok {
+			used = &deprecatedUsed{fields: make(map[string]bool)}
+			root[keyOfDeprecated] = used
+		}
+		used.Lock()
+		key := usage.Type + "." + usage.Field
+		reported := used.fields[key]
+		used.fields[key] = true
+		used.Unlock()
+		if reported {
+			return
+		}
+	}
+
+	ctx := p.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	objm.deprecatedUsageFn(ctx, r, usage)
+}
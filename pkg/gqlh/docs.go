@@ -0,0 +1,73 @@
+package gqlh
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/seerx/gql/pkg/def"
+)
+
+// 从 go 文档注释中提取的描述信息，由 gqldoc 生成的代码注册
+// key 的形式为 包路径.类型名称、包路径.类型名称.字段名称（或方法名称）、包路径.函数名称
+var docs = struct {
+	sync.RWMutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// RegisterDocs 注册文档注释中的描述信息，作为没有指定描述信息时的默认值
+// 通常由 gqldoc 生成的代码在 init 中调用
+func RegisterDocs(items map[string]string) {
+	docs.Lock()
+	defer docs.Unlock()
+	for key, doc := range items {
+		docs.m[key] = doc
+	}
+}
+
+// findDoc 查找描述信息，找不到时返回空字符串
+func findDoc(key string) string {
+	docs.RLock()
+	defer docs.RUnlock()
+	return docs.m[key]
+}
+
+// typeDoc 类型的文档注释
+func typeDoc(typ reflect.Type) string {
+	if typ.PkgPath() == "" || typ.Name() == "" {
+		return ""
+	}
+	return findDoc(typ.PkgPath() + "." + typ.Name())
+}
+
+// fieldDoc 结构字段的文档注释，嵌入结构中的字段使用其所在结构的注释
+// @param parent 字段所在的结构类型
+func fieldDoc(parent reflect.Type, field *reflect.StructField) string {
+	owner := parent
+	for _, x := range field.Index[:len(field.Index)-1] {
+		owner = owner.Field(x).Type
+		if owner.Kind() == reflect.Ptr {
+			owner = owner.Elem()
+		}
+	}
+	if owner.PkgPath() == "" || owner.Name() == "" {
+		return ""
+	}
+	return findDoc(owner.PkgPath() + "." + owner.Name() + "." + field.Name)
+}
+
+// funcDoc 函数或方法的文档注释
+func funcDoc(fn *def.FuncInfo) string {
+	var owner reflect.Type
+	if fn.Struct != nil {
+		owner = reflect.TypeOf(fn.Struct)
+	} else if fn.Receiver != nil {
+		owner = fn.Receiver
+	}
+	if owner == nil {
+		return findDoc(fn.Pkg + "." + fn.Name)
+	}
+	if owner.Kind() == reflect.Ptr {
+		owner = owner.Elem()
+	}
+	return findDoc(owner.PkgPath() + "." + owner.Name() + "." + fn.Name)
+}
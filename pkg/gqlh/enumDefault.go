@@ -0,0 +1,38 @@
+package gqlh
+
+import (
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+var enumDefaultOnce sync.Once
+
+// useEnumDefaultName 修改内省中 __InputValue.defaultValue 的解析函数，枚举类型的默认值输出枚举值的名称
+// 默认值保存的是 go 常量（如 OrderStatus("paid")），graphql-go 把它输出为字符串 "\"paid\""，
+// 客户端（GraphiQL、代码生成工具）需要的是 PAID；其他类型的默认值仍使用 graphql-go 的解析函数
+func useEnumDefaultName() {
+	enumDefaultOnce.Do(func() {
+		field := graphql.InputValueType.Fields()["defaultValue"]
+		resolve := field.Resolve
+		field.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+			var typ graphql.Type
+			var value interface{}
+			switch v := p.Source.(type) {
+			case *graphql.Argument:
+				typ, value = v.Type, v.DefaultValue
+			case *graphql.InputObjectField:
+				typ, value = v.Type, v.DefaultValue
+			}
+			if nonNull, ok := typ.(*graphql.NonNull); ok {
+				typ = nonNull.OfType
+			}
+			if enum, ok := typ.(*graphql.Enum); ok && value != nil {
+				if name, ok := enum.Serialize(value).(string); ok {
+					return name, nil
+				}
+			}
+			return resolve(p)
+		}
+	})
+}
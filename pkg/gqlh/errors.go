@@ -0,0 +1,168 @@
+package gqlh
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/location"
+)
+
+// 错误代码，在错误信息的 extensions.code 中返回
+const (
+	CodeInternal                = "INTERNAL_SERVER_ERROR"     // 未知错误，如没有错误代码的 error、未知的 panic
+	CodeGraphQLValidationFailed = "GRAPHQL_VALIDATION_FAILED" // 请求语句不正确，如语法错误、字段不存在
+	CodeBadRequest              = "BAD_REQUEST"               // 请求方式不正确，如通过 HTTP POST 执行订阅
+	CodeValidationFailed        = "VALIDATION_FAILED"         // 参数验证失败
+	CodeInjectFailed            = "INJECT_FAILED"             // 注入函数失败
+	CodeUnauthenticated         = "UNAUTHENTICATED"           // 未登录
+	CodeForbidden               = "FORBIDDEN"                 // 没有权限
+	CodeNotFound                = "NOT_FOUND"                 // 数据不存在
+)
+
+// Error 带有错误代码的错误，函数返回此错误时，错误信息的 extensions 中包含 code 和 Extra 中的内容
+// 前端可以根据 code 区分错误，不需要解析错误信息，例如 return nil, gqlh.NewError(gqlh.CodeNotFound, "订单不存在")
+type Error struct {
+	Code    string                 // 错误代码
+	Message string                 // 错误信息
+	Extra   map[string]interface{} // 附加信息，在错误信息的 extensions 中返回
+	Cause   error                  // 原始错误
+}
+
+// NewError 创建错误
+func NewError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf 使用格式化字符串创建错误
+func Errorf(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WrapError 为已有的错误加上错误代码，错误信息与原始错误相同
+func WrapError(code string, cause error) *Error {
+	if cause == nil {
+		return nil
+	}
+	return &Error{Code: code, Message: cause.Error(), Cause: cause}
+}
+
+// Error 错误信息
+func (e *Error) Error() string {
+	if e.Message == "" && e.Cause != nil {
+		return e.Cause.Error()
+	}
+	return e.Message
+}
+
+// Unwrap 原始错误
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Extensions 错误信息的 extensions，包括 code
+func (e *Error) Extensions() map[string]interface{} {
+	ext := make(map[string]interface{}, len(e.Extra)+1)
+	for k, v := range e.Extra {
+		ext[k] = v
+	}
+	if e.Code != "" {
+		ext["code"] = e.Code
+	}
+	return ext
+}
+
+// WithExtension 加入附加信息，返回错误本身
+func (e *Error) WithExtension(key string, value interface{}) *Error {
+	if e.Extra == nil {
+		e.Extra = make(map[string]interface{})
+	}
+	e.Extra[key] = value
+	return e
+}
+
+// ErrorCode 获取错误代码，包括被包装的错误，没有错误代码时返回空
+func ErrorCode(err error) string {
+	code, _ := extensionsOf(err)["code"].(string)
+	return code
+}
+
+// FormatError 默认的 FormatErrorFn，错误信息的 extensions 中总是包含 code
+// 请求语句不正确时为 GRAPHQL_VALIDATION_FAILED，没有错误代码的 error 为 INTERNAL_SERVER_ERROR
+func FormatError(err error) gqlerrors.FormattedError {
+	if err == nil {
+		// 没有原始错误，如自定义的 FormattedError
+		return gqlerrors.FormattedError{
+			Message:    "未知错误",
+			Locations:  []location.SourceLocation{},
+			Extensions: map[string]interface{}{"code": CodeInternal},
+		}
+	}
+	formatted := gqlerrors.FormatError(err)
+	if located, ok := err.(*gqlerrors.Error); ok && located.OriginalError == nil {
+		// 语句解析、验证错误，或者变量的值不正确
+		formatted.Extensions = map[string]interface{}{"code": CodeGraphQLValidationFailed}
+		return formatted
+	}
+	ext := extensionsOf(err)
+	if ext == nil {
+		ext = make(map[string]interface{})
+	}
+	if _, ok := ext["code"]; !ok {
+		ext["code"] = CodeInternal
+	}
+	formatted.Extensions = ext
+	return formatted
+}
+
+// withExtensions 函数返回的错误包装了带有 extensions 的错误时（如 fmt.Errorf("...: %w", err)），
+// 转换为 Error，使 graphql 可以输出 extensions，并加入出错字段的路径
+func withExtensions(err error, path []interface{}) error {
+	if _, ok := err.(*ResolveError); ok {
+		return err
+	}
+	ext := extensionsOf(err)
+	if ext == nil {
+		return err
+	}
+	code, _ := ext["code"].(string)
+	delete(ext, "code")
+	if path != nil {
+		ext["path"] = path
+	}
+	return &Error{Code: code, Message: err.Error(), Extra: ext, Cause: err}
+}
+
+// extensionsOf 获取错误或者被包装的错误中的 extensions，返回的是副本
+func extensionsOf(err error) map[string]interface{} {
+	for err != nil {
+		var ext map[string]interface{}
+		switch e := err.(type) {
+		case gqlerrors.ExtendedError:
+			ext = e.Extensions()
+		case gqlerrors.FormattedError:
+			ext = e.Extensions
+		}
+		if ext != nil {
+			copied := make(map[string]interface{}, len(ext)+1)
+			for k, v := range ext {
+				copied[k] = v
+			}
+			return copied
+		}
+		err = unwrapError(err)
+	}
+	return nil
+}
+
+// unwrapError 获取被包装的错误
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *gqlerrors.Error:
+		return e.OriginalError
+	case gqlerrors.FormattedError:
+		return e.OriginalError()
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
+	}
+	return nil
+}
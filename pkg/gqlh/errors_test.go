@@ -0,0 +1,90 @@
+package gqlh_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/seerx/gql"
+	"github.com/seerx/gql/pkg/gqlh"
+)
+
+type Account struct {
+	ID string
+}
+
+type AccountArg struct {
+	ID string
+}
+
+func NotFound(a *AccountArg) (*Account, error) {
+	return nil, gqlh.NewError(gqlh.CodeNotFound, "账户不存在").WithExtension("id", a.ID)
+}
+
+func Wrapped() (*Account, error) {
+	return nil, gqlh.WrapError(gqlh.CodeUnauthenticated, errors.New("未登录"))
+}
+
+func Plain() (*Account, error) {
+	return nil, errors.New("数据库错误")
+}
+
+func Panics() (*Account, error) {
+	var a *Account
+	return &Account{ID: a.ID}, nil
+}
+
+func TestErrorExtensions(t *testing.T) {
+	g := gql.NewGQL()
+	g.SetPanicLogger(func(ctx context.Context, err *gqlh.ResolveError) {})
+	g.RegisterQuery(NotFound)
+	g.RegisterQuery(Wrapped)
+	g.RegisterQuery(Plain)
+	g.RegisterQuery(Panics)
+	h := newHandler(t, g)
+
+	cases := []struct {
+		query   string
+		message string
+		ext     map[string]interface{}
+	}{
+		{`{NotFound(AccountArg: {ID: "7"}) {ID}}`, "账户不存在",
+			map[string]interface{}{"code": gqlh.CodeNotFound, "id": "7"}},
+		{`{Wrapped {ID}}`, "未登录", map[string]interface{}{"code": gqlh.CodeUnauthenticated}},
+		{`{Plain {ID}}`, "数据库错误", map[string]interface{}{"code": gqlh.CodeInternal}},
+		{`{Panics {ID}}`, "服务器内部错误", map[string]interface{}{"code": gqlh.CodeInternal}},
+		{`{Plain {Name}}`, "", map[string]interface{}{"code": gqlh.CodeGraphQLValidationFailed}},
+	}
+	for _, c := range cases {
+		res := post(t, h, c.query, nil)
+		if len(res.Errors) != 1 {
+			t.Errorf("%s: 期望一个错误，实际 %v", c.query, res.Errors)
+			continue
+		}
+		e := res.Errors[0]
+		if c.message != "" && e.Message != c.message {
+			t.Errorf("%s: 错误信息为 %s，期望 %s", c.query, e.Message, c.message)
+		}
+		for k, v := range c.ext {
+			if e.Extensions[k] != v {
+				t.Errorf("%s: extensions 为 %v，期望 %s 为 %v", c.query, e.Extensions, k, v)
+			}
+		}
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	err := gqlh.WrapError(gqlh.CodeForbidden, errors.New("没有权限"))
+	if code := gqlh.ErrorCode(err); code != gqlh.CodeForbidden {
+		t.Errorf("错误代码为 %s", code)
+	}
+	if code := gqlh.ErrorCode(errors.New("x")); code != "" {
+		t.Errorf("没有错误代码的 error 返回 %s", code)
+	}
+	if gqlh.WrapError(gqlh.CodeForbidden, nil) != nil {
+		t.Error("WrapError(nil) 应该返回 nil")
+	}
+	if f := gqlh.FormatError(nil); f.Extensions["code"] != gqlh.CodeInternal {
+		t.Errorf("FormatError(nil) 的 extensions 为 %v", f.Extensions)
+	}
+}
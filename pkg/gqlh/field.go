@@ -6,5 +6,8 @@ import "github.com/seerx/gql/pkg/utils"
 type Field struct {
 	Name     string
 	JSONName string // 适用于结构字段
-	Prop     *utils.TypeProp
+	Index    []int  // 结构字段的索引路径，嵌入结构中的字段有多级
+	// 输入字段的默认值，来自 gql:"default=..." 标记
+	DefaultValue interface{}
+	Prop         *utils.TypeProp
 }
@@ -0,0 +1,102 @@
+package gqlh
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/seerx/gql/pkg/utils"
+)
+
+// FieldNaming 字段、Query 和 Mutation 的命名策略
+// 只作用于没有在 json tag 中指定名称的字段，以及函数（方法）名称
+type FieldNaming int
+
+const (
+	// FieldNamingAsIs 保持 go 的名称，如 ID、UserName
+	FieldNamingAsIs FieldNaming = iota
+	// FieldNamingCamelCase 小驼峰，如 id、userName、urlPath
+	FieldNamingCamelCase
+	// FieldNamingSnakeCase 下划线分隔，如 id、user_name、url_path
+	FieldNamingSnakeCase
+)
+
+// Convert 按照命名策略转换名称
+func (n FieldNaming) Convert(name string) string {
+	switch n {
+	case FieldNamingCamelCase:
+		words := splitWords(name)
+		if len(words) == 0 {
+			return name
+		}
+		words[0] = strings.ToLower(words[0])
+		for i := 1; i < len(words); i++ {
+			runes := []rune(words[i])
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+		return strings.Join(words, "")
+	case FieldNamingSnakeCase:
+		words := splitWords(name)
+		for i, w := range words {
+			words[i] = strings.ToLower(w)
+		}
+		return strings.Join(words, "_")
+	}
+	return name
+}
+
+// splitWords 按照大小写拆分单词，连续的大写字母作为一个单词，如 UserURLPath 拆分为 User、URL、Path
+func splitWords(name string) []string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		split := false
+		if cur == '_' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if unicode.IsUpper(cur) {
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				// userName -> user Name
+				split = true
+			} else if unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				// URLPath -> URL Path
+				split = true
+			}
+		}
+		if split && i > start {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// SetFieldNaming 设置字段命名策略，必须在生成 schema 之前设置
+func (tm *TypeManager) SetFieldNaming(naming FieldNaming) {
+	tm.fieldNaming = naming
+}
+
+// FieldName 获取结构字段对应的 graphql 字段名称，返回空字符串时表示忽略该字段
+// json 或 gql tag 中指定的名称保持不变，其他按照命名策略转换
+func (tm *TypeManager) FieldName(field *reflect.StructField) string {
+	name := utils.ParseStructFieldName(field)
+	if name == "" || utils.HasExplicitName(field) {
+		return name
+	}
+	return tm.fieldNaming.Convert(name)
+}
+
+// ResolverName 获取函数（方法）对应的 graphql 字段名称
+func (tm *TypeManager) ResolverName(name string) string {
+	return tm.fieldNaming.Convert(name)
+}
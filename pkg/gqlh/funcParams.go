@@ -1,6 +1,7 @@
 package gqlh
 
 import (
+	"context"
 	"io"
 	"reflect"
 
@@ -31,6 +32,32 @@ func (*ipStruct) isInjectInterface() bool {
 	return false
 }
 
+// 结构字段方法的接收者，即 graphql 的 Source
+type ipSource struct {
+	typ reflect.Type // 接收者类型（结构指针）
+}
+
+func (ips *ipSource) createValue(structInstance interface{},
+	gqlParam *graphql.ResolveParams,
+	validator *InputValidator,
+	requestValue reflect.Value) reflect.Value {
+	val := reflect.ValueOf(gqlParam.Source)
+	if val.Type() == ips.typ {
+		return val
+	}
+	if val.Type() == ips.typ.Elem() {
+		// 结构值，转换为指针
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		return ptr
+	}
+	return val
+}
+
+func (*ipSource) isInjectInterface() bool {
+	return false
+}
+
 // Graphql 原始参数
 type ipGraphqlResolveParams struct {
 	valueIsPtr bool
@@ -50,6 +77,30 @@ func (ipgrp *ipGraphqlResolveParams) isInjectInterface() bool {
 	return false
 }
 
+// 请求的 context.Context，包含请求的取消和超时信息
+type ipContext struct {
+}
+
+func (*ipContext) createValue(structInstance interface{},
+	gqlParam *graphql.ResolveParams,
+	validator *InputValidator,
+	requestValue reflect.Value) reflect.Value {
+	if gqlParam.Context != nil {
+		return reflect.ValueOf(gqlParam.Context)
+	}
+	// 没有 context 时，从 root value 中获取
+	if root, ok := gqlParam.Info.RootValue.(map[string]interface{}); ok {
+		if ctx, ok := root[keyOfConext].(context.Context); ok {
+			return reflect.ValueOf(ctx)
+		}
+	}
+	return reflect.ValueOf(context.Background())
+}
+
+func (*ipContext) isInjectInterface() bool {
+	return false
+}
+
 // 参数验证参数
 type ipValidator struct {
 }
@@ -0,0 +1,57 @@
+package gqlh_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphql-go/handler"
+	"github.com/seerx/gql"
+)
+
+// response graphql 请求的结果
+type response struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// newHandler 生成 schema 并创建 http handler，注册失败时测试失败
+func newHandler(t *testing.T, g *gql.GQL) http.Handler {
+	t.Helper()
+	if _, err := g.GetSchema(); err != nil {
+		t.Fatal(err)
+	}
+	return g.NewHandler(&handler.Config{})
+}
+
+// post 以 POST 方式执行 graphql 请求
+func post(t *testing.T, h http.Handler, query string, variables map[string]interface{}) *response {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	res := &response{}
+	if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+		t.Fatalf("%s: %s", err.Error(), w.Body.String())
+	}
+	return res
+}
+
+// path 按路径获取结果中的值，如 path(res.Data, "orders", "status")
+func path(data interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		data = m[key]
+	}
+	return data
+}
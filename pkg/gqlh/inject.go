@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"runtime/debug"
 
 	"github.com/graphql-go/graphql"
 	"github.com/seerx/gql/pkg/utils"
@@ -41,7 +42,13 @@ type InjectInfo struct {
 }
 
 // CallFn 调用注入函数
+// 注入函数中的 panic 由 resolver 转换为错误，panic 的值是 error 时返回其错误信息
 func (i *InjectInfo) CallFn(p *graphql.ResolveParams) reflect.Value {
+	defer func() {
+		if err := recover(); err != nil {
+			panic(&injectPanic{fn: i.FnInfo.FullName(), value: err, stack: debug.Stack()})
+		}
+	}()
 	var ok bool
 	root, ok := p.Info.RootValue.(map[string]interface{})
 	if !ok {
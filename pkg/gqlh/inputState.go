@@ -0,0 +1,132 @@
+package gqlh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// InputState 输入参数的提交状态
+type InputState int
+
+const (
+	// InputAbsent 未提交
+	InputAbsent InputState = iota
+	// InputNull 显式提交了 null
+	InputNull
+	// InputSet 提交了值
+	InputSet
+)
+
+type contextKey string
+
+const keyOfRawVariables contextKey = "gql.rawVariables"
+
+// CaptureVariables 读取请求中原始的 variables 并保存到 context 中
+// graphql-go 会丢弃值为 null 的输入，需要原始 variables 区分 null 和未提交
+func CaptureVariables(r *http.Request) *http.Request {
+	var raw []byte
+	if r.Method == http.MethodGet {
+		raw = []byte(r.URL.Query().Get("variables"))
+	} else if r.Method == http.MethodPost && r.Body != nil &&
+		strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return r
+		}
+		// 重置 body，graphql handler 还要读取
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		var req struct {
+			Variables json.RawMessage `json:"variables"`
+		}
+		if json.Unmarshal(body, &req) != nil {
+			return r
+		}
+		raw = req.Variables
+	}
+	if len(raw) == 0 {
+		return r
+	}
+
+	var vars map[string]interface{}
+	if json.Unmarshal(raw, &vars) != nil {
+		// variables 可能是 JSON 字符串
+		var str string
+		if json.Unmarshal(raw, &str) != nil || json.Unmarshal([]byte(str), &vars) != nil {
+			return r
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), keyOfRawVariables, vars))
+}
+
+// rawArguments 获取 resolver 参数的原始值，保留显式提交的 null
+func rawArguments(p *graphql.ResolveParams) map[string]interface{} {
+	raw := make(map[string]interface{})
+	if len(p.Info.FieldASTs) == 0 {
+		return raw
+	}
+	var vars map[string]interface{}
+	if p.Context != nil {
+		vars, _ = p.Context.Value(keyOfRawVariables).(map[string]interface{})
+	}
+	if vars == nil {
+		// 没有原始 variables，使用 graphql-go 处理后的值
+		vars = p.Info.VariableValues
+	}
+	for _, arg := range p.Info.FieldASTs[0].Arguments {
+		if val, ok := rawValue(arg.Value, vars); ok {
+			raw[arg.Name.Value] = val
+		}
+	}
+	return raw
+}
+
+// rawValue 把语句中的值转换为原始值，返回 false 表示未提交（引用了未提交的变量）
+func rawValue(value ast.Value, vars map[string]interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case *ast.Variable:
+		val, ok := vars[v.Name.Value]
+		return val, ok
+	case *ast.ObjectValue:
+		obj := make(map[string]interface{})
+		for _, f := range v.Fields {
+			if val, ok := rawValue(f.Value, vars); ok {
+				obj[f.Name.Value] = val
+			}
+		}
+		return obj, true
+	case *ast.ListValue:
+		var list []interface{}
+		for _, item := range v.Values {
+			val, _ := rawValue(item, vars)
+			list = append(list, val)
+		}
+		return list, true
+	}
+	return value.GetValue(), true
+}
+
+// inputState 根据输入值和原始值判断提交状态
+// 有原始值时以原始值为准，输入值中可能包含 graphql 填充的默认值
+func inputState(input map[string]interface{}, raw map[string]interface{}, name string) InputState {
+	if raw != nil {
+		val, ok := raw[name]
+		if !ok {
+			return InputAbsent
+		}
+		if val == nil {
+			return InputNull
+		}
+		return InputSet
+	}
+	if _, ok := input[name]; ok {
+		return InputSet
+	}
+	return InputAbsent
+}
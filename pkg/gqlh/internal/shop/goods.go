@@ -0,0 +1,8 @@
+// Package shop 测试类型名称冲突使用的结构，与 store.Goods 同名
+package shop
+
+// Goods 商品
+type Goods struct {
+	ID    string
+	Price float64
+}
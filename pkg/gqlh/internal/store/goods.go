@@ -0,0 +1,8 @@
+// Package store 测试类型名称冲突使用的结构，与 shop.Goods 同名
+package store
+
+// Goods 库存商品
+type Goods struct {
+	ID    string
+	Stock int
+}
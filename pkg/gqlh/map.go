@@ -0,0 +1,170 @@
+package gqlh
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/seerx/gql/pkg/utils"
+)
+
+// JSONScalar map 默认对应的 graphql 类型，以 JSON 对象的形式输入输出
+var JSONScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "JSON object",
+	Serialize: func(value interface{}) interface{} {
+		val := reflect.ValueOf(value)
+		if val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return nil
+			}
+			return val.Elem().Interface()
+		}
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: jsonLiteralValue,
+})
+
+// jsonLiteralValue 把 graphql 语句中的对象、列表字面值转换为 go 值
+func jsonLiteralValue(valueAST ast.Value) interface{} {
+	switch v := valueAST.(type) {
+	case *ast.ObjectValue:
+		obj := map[string]interface{}{}
+		for _, field := range v.Fields {
+			obj[field.Name.Value] = jsonLiteralValue(field.Value)
+		}
+		return obj
+	case *ast.ListValue:
+		list := make([]interface{}, 0, len(v.Values))
+		for _, item := range v.Values {
+			list = append(list, jsonLiteralValue(item))
+		}
+		return list
+	case *ast.EnumValue:
+		return v.Value
+	}
+	return literalValue(valueAST)
+}
+
+// IsKeyValueMap 字段是否是使用 gql:"map=kv" 标记的 map
+// 此类 map 以 [{key, value}] 列表的形式输入输出，列表按照 key 排序
+func IsKeyValueMap(field *reflect.StructField) bool {
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Map {
+		return false
+	}
+	return utils.ParseGqlTagValue(field, "map") == "kv"
+}
+
+// KeyValueType 获取 map 对应的 [{key, value}] 列表类型
+// 对象名称为 键类型名称 + 值类型名称 + KeyValue，如 StringIntKeyValue，map[string]string 对应 KeyValue
+// @param typ map 类型
+// @param input 是否生成输入对象
+func (tm *TypeManager) KeyValueType(typ reflect.Type, input bool) graphql.Output {
+	keyType, _, err := utils.TypeToGraphQLType(typ.Key(), tm.FindType)
+	if err != nil {
+		panic(err)
+	}
+	valBase := utils.BaseType(typ.Elem(), tm.FindType)
+	valType, isStruct, err := utils.TypeToGraphQLType(valBase, tm.FindType)
+	if err != nil {
+		panic(err)
+	}
+	if isStruct || keyType == nil {
+		panic(fmt.Errorf("Map [%s] with struct key or value can not use map=kv, use map=json instead", typ.String()))
+	}
+
+	valName := valType.Name()
+	if typ.Elem().Kind() == reflect.Slice && !tm.IsScalar(typ.Elem()) {
+		valName += "List"
+	}
+	name := keyType.Name() + valName + "KeyValue"
+	if name == "StringStringKeyValue" {
+		name = "KeyValue"
+	}
+	if input {
+		name = "input" + name
+	}
+
+	obj, ok := tm.keyValueMap[name]
+	if !ok {
+		value := utils.WrapType(typ.Elem(), valBase, valType)
+		if input {
+			obj = graphql.NewInputObject(graphql.InputObjectConfig{
+				Name: name,
+				Fields: graphql.InputObjectConfigFieldMap{
+					"key":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(keyType)},
+					"value": &graphql.InputObjectFieldConfig{Type: value},
+				},
+			})
+		} else {
+			obj = graphql.NewObject(graphql.ObjectConfig{
+				Name: name,
+				Fields: graphql.Fields{
+					"key":   &graphql.Field{Type: graphql.NewNonNull(keyType)},
+					"value": &graphql.Field{Type: value},
+				},
+			})
+		}
+		tm.keyValueMap[name] = obj
+	}
+	return graphql.NewList(graphql.NewNonNull(obj))
+}
+
+// resolveKeyValue 把 map 字段的值转换为按照 key 排序的 [{key, value}] 列表
+// @param resolve 获取 map 字段值的函数，为 nil 时使用 graphql 默认的函数
+func resolveKeyValue(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	if resolve == nil {
+		resolve = graphql.DefaultResolveFn
+	}
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		res, err := resolve(p)
+		if err != nil || res == nil {
+			return res, err
+		}
+		val := reflect.ValueOf(res)
+		if val.Kind() == reflect.Ptr {
+			val = val.Elem()
+		}
+		if !val.IsValid() || val.IsNil() {
+			return nil, nil
+		}
+
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessOfKey(keys[i], keys[j])
+		})
+		list := make([]map[string]interface{}, 0, len(keys))
+		for _, key := range keys {
+			list = append(list, map[string]interface{}{
+				"key":   key.Interface(),
+				"value": val.MapIndex(key).Interface(),
+			})
+		}
+		return list, nil
+	}
+}
+
+// lessOfKey 比较 map 的 key，数值按照大小排序，其他按照字符串排序
+func lessOfKey(a, b reflect.Value) bool {
+	kind := a.Kind()
+	switch {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return a.Int() < b.Int()
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case utils.IsFloatType(kind):
+		return a.Float() < b.Float()
+	case utils.IsStringType(kind):
+		return a.String() < b.String()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
@@ -0,0 +1,61 @@
+package gqlh
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// Invocation 一次 resolver 调用的信息
+type Invocation struct {
+	Info *RegisterInfo // 函数的注册信息，Info.Type 为 Query、Mutation、Subscription 或 Field（结构字段方法）
+	// Params graphql 参数，可以在调用 next 之前修改 Params.Args
+	Params graphql.ResolveParams
+}
+
+// Type 调用的类型，Query、Mutation、Subscription 或 Field
+func (inv *Invocation) Type() string {
+	return inv.Info.Type
+}
+
+// ResolveFn 执行 resolver
+type ResolveFn func(inv *Invocation) (interface{}, error)
+
+// Middleware resolver 中间件，包装每一次 Query、Mutation、Subscription 函数和结构字段方法的调用
+// 调用 next 执行下一个中间件或者函数本身；不调用 next 直接返回时，函数不会被执行
+// 可以修改 next 返回的结果和错误，例如：
+//
+//	func(inv *gqlh.Invocation, next gqlh.ResolveFn) (interface{}, error) {
+//		start := time.Now()
+//		out, err := next(inv)
+//		log.Println(inv.Type(), inv.Info.Name, time.Since(start))
+//		return out, err
+//	}
+type Middleware func(inv *Invocation, next ResolveFn) (interface{}, error)
+
+// Use 添加中间件，先添加的在外层，必须在生成 schema 之前调用
+func (rm *ResolverManager) Use(middlewares ...Middleware) {
+	rm.middlewares = append(rm.middlewares, middlewares...)
+}
+
+// resolveWithMiddlewares 使用中间件包装 resolve 函数
+func (r *Resolver) resolveWithMiddlewares(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	middlewares := r.manager.middlewares
+	if len(middlewares) == 0 {
+		return resolve
+	}
+
+	var fn ResolveFn = func(inv *Invocation) (interface{}, error) {
+		return resolve(inv.Params)
+	}
+	for n := len(middlewares) - 1; n >= 0; n-- {
+		mw, next := middlewares[n], fn
+		fn = func(inv *Invocation) (interface{}, error) {
+			return mw(inv, next)
+		}
+	}
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return fn(&Invocation{
+			Info:   r.info,
+			Params: p,
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package gqlh
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// ResolveError resolver 执行过程中的 panic 转换成的错误
+// 参数验证失败（InputValidator.Requires、gql tag 中的验证规则）和注入函数 panic 的 error 是可预期的，
+// 直接返回其错误信息；其他 panic 是未知错误，不返回具体信息，记录日志
+type ResolveError struct {
+	Code    string        // 错误代码
+	Message string        // 错误信息
+	Path    []interface{} // 出错字段的路径
+	Cause   error         // 原始错误
+	Panic   interface{}   // 未知错误 panic 的值
+	Stack   []byte        // 未知错误的调用栈
+}
+
+// Error 错误信息
+func (e *ResolveError) Error() string {
+	return e.Message
+}
+
+// Unwrap 原始错误
+func (e *ResolveError) Unwrap() error {
+	return e.Cause
+}
+
+// Extensions 错误信息的 extensions，包括 code 和 path，以及原始错误的 extensions
+func (e *ResolveError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{}
+	if extended, ok := e.Cause.(gqlerrors.ExtendedError); ok {
+		for k, v := range extended.Extensions() {
+			ext[k] = v
+		}
+	}
+	ext["code"] = e.Code
+	if e.Path != nil {
+		ext["path"] = e.Path
+	}
+	return ext
+}
+
+// ValidationError 参数验证失败，InputValidator.Requires 和验证规则以此 panic
+type ValidationError struct {
+	Param   string // 参数名称，嵌套结构使用 . 分隔
+	Message string
+}
+
+// Error 错误信息
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Extensions 错误信息的 extensions
+func (e *ValidationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":  CodeValidationFailed,
+		"param": e.Param,
+	}
+}
+
+// injectPanic 注入函数中的 panic
+type injectPanic struct {
+	fn    string      // 注入函数名称
+	value interface{} // panic 的值
+	stack []byte
+}
+
+// PanicLogger 记录未知 panic 的函数，默认使用 log 输出错误和调用栈
+type PanicLogger func(ctx context.Context, err *ResolveError)
+
+// defaultPanicLogger 默认的 panic 日志
+func defaultPanicLogger(ctx context.Context, err *ResolveError) {
+	log.Printf("gql: panic in %v: %v\n%s", err.Path, err.Panic, err.Stack)
+}
+
+// log 记录未知 panic，logger 为 nil 时使用默认的日志
+func (logger PanicLogger) log(ctx context.Context, err *ResolveError) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if logger == nil {
+		logger = defaultPanicLogger
+	}
+	logger(ctx, err)
+}
+
+// SetPanicLogger 设置记录未知 panic 的函数，为 nil 时使用默认的日志
+func (objm *ResponseObjectManager) SetPanicLogger(logger PanicLogger) {
+	objm.panicLogger = logger
+}
+
+// PanicLogger 记录未知 panic 的函数，没有设置时为 nil
+func (objm *ResponseObjectManager) PanicLogger() PanicLogger {
+	return objm.panicLogger
+}
+
+// resolveWithRecover 包装 resolve 函数，把 panic 转换为 ResolveError，返回的错误加上 extensions
+func (objm *ResponseObjectManager) resolveWithRecover(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (out interface{}, err error) {
+		defer func() {
+			if value := recover(); value != nil {
+				var path []interface{}
+				if p.Info.Path != nil {
+					path = p.Info.Path.AsArray()
+				}
+				resolveErr := newResolveError(value, path)
+				if resolveErr.Code == CodeInternal {
+					objm.panicLogger.log(p.Context, resolveErr)
+				}
+				out, err = nil, resolveErr
+			}
+		}()
+		out, err = resolve(p)
+		if err != nil && p.Info.Path != nil {
+			err = withExtensions(err, p.Info.Path.AsArray())
+		}
+		return out, err
+	}
+}
+
+// newResolveError 根据 panic 的值生成错误
+func newResolveError(value interface{}, path []interface{}) *ResolveError {
+	stack := debug.Stack()
+	switch v := value.(type) {
+	case *ValidationError:
+		return &ResolveError{Code: CodeValidationFailed, Message: v.Message, Path: path, Cause: v}
+	case *injectPanic:
+		if err, ok := v.value.(error); ok && !isRuntimeError(err) {
+			return &ResolveError{Code: CodeInjectFailed, Message: err.Error(), Path: path, Cause: err}
+		}
+		value, stack = fmt.Sprintf("%v (inject %s)", v.value, v.fn), v.stack
+	}
+	return &ResolveError{
+		Code:    CodeInternal,
+		Message: "服务器内部错误",
+		Path:    path,
+		Panic:   value,
+		Stack:   stack,
+	}
+}
+
+// isRuntimeError 是否是运行时错误，如空指针、数组越界
+func isRuntimeError(err error) bool {
+	_, ok := err.(runtime.Error)
+	return ok
+}
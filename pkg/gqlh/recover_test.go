@@ -0,0 +1,35 @@
+package gqlh
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestSubscriptionPanicLogger(t *testing.T) {
+	objm := NewResponseObjectManager(NewTypeManager())
+	cfg := &SubscriptionConfig{ResponseObjectManager: objm}
+
+	// 创建订阅处理器之后设置的函数同样有效
+	var logged []*ResolveError
+	objm.SetPanicLogger(func(ctx context.Context, err *ResolveError) {
+		logged = append(logged, err)
+	})
+	err := newResolveError("boom", nil)
+	cfg.logPanic(context.Background(), err)
+	if len(logged) != 1 || logged[0] != err {
+		t.Fatalf("没有使用 SetPanicLogger 设置的函数记录 panic: %v", logged)
+	}
+
+	// 没有设置时使用默认的日志
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	objm.SetPanicLogger(nil)
+	cfg.logPanic(context.Background(), err)
+	(&SubscriptionConfig{}).logPanic(context.Background(), err)
+	if len(logged) != 1 {
+		t.Fatal("SetPanicLogger(nil) 之后仍然使用原来的函数")
+	}
+}
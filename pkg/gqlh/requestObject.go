@@ -2,6 +2,7 @@ package gqlh
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/graphql-go/graphql"
 	"github.com/seerx/gql/pkg/utils"
@@ -9,24 +10,33 @@ import (
 
 // RequestObject 请求的参数对象定义
 type RequestObject struct {
-	Object graphql.Input
-	Param  *Field   // 对应的参数
-	Name   string   // 结构名称
-	Fields []*Field // 结构中的字段列表
+	Object   graphql.Input
+	Param    *Field                            // 对应的参数
+	Name     string                            // 结构名称
+	Fields   []*Field                          // 结构中的字段列表
+	FieldMap graphql.InputObjectConfigFieldMap // graphql 字段定义
+	Flatten  bool                              // 结构中有 _ struct{} `gql:"flatten"` 标记
 }
 
 // RequestObjectManager  请求对象管理
 type RequestObjectManager struct {
-	objectMap map[string]*RequestObject
+	objectMap   map[string]*RequestObject
+	typeManager *TypeManager
 }
 
 // NewRequestObjectManager 创建管理器
-func NewRequestObjectManager() *RequestObjectManager {
+func NewRequestObjectManager(typeManager *TypeManager) *RequestObjectManager {
 	return &RequestObjectManager{
-		objectMap: make(map[string]*RequestObject),
+		objectMap:   make(map[string]*RequestObject),
+		typeManager: typeManager,
 	}
 }
 
+// FindObject 根据结构类型查找已经注册的对象
+func (objm *RequestObjectManager) FindObject(typ reflect.Type) *RequestObject {
+	return objm.objectMap[utils.ParseTypeProp(typ).Key()]
+}
+
 // FindOrRegisterObject 查找查询对象，如果找不到则注册
 func (objm *RequestObjectManager) FindOrRegisterObject(field *Field, name string) *RequestObject {
 	list := field.Prop.IsList
@@ -64,7 +74,7 @@ func (objm *RequestObjectManager) FindOrRegisterObject(field *Field, name string
 	if !ok {
 		if name == "" {
 			// name 为空时，必须找到对象
-			panic(fmt.Errorf("Cann't find input object %s", field))
+			panic(fmt.Errorf("Cann't find input object %s", field.Prop))
 		}
 		// 没有找到，注册
 		objFields := graphql.InputObjectConfigFieldMap{}
@@ -76,40 +86,74 @@ func (objm *RequestObjectManager) FindOrRegisterObject(field *Field, name string
 		// 注册单个查询对象
 		gobj := graphql.NewInputObject(
 			graphql.InputObjectConfig{
-				Name:   "input" + name,
-				Fields: objFields,
+				Name:        "input" + objm.typeManager.TypeName(p.RealType),
+				Description: typeDoc(p.RealType),
+				Fields:      objFields,
 			})
 
 		obj = &RequestObject{
-			Object: gobj,
-			Param:  field,
-			Name:   name,
-			Fields: fields,
+			Object:   gobj,
+			Param:    field,
+			Name:     name,
+			Fields:   fields,
+			FieldMap: objFields,
 		}
 		objm.objectMap[key] = obj
 
-		for n := 0; n < p.RealType.NumField(); n++ {
-			field := p.RealType.Field(n)
-			prop := utils.ParseTypeProp(field.Type)
-			id := utils.ParseStructFieldName(&field)
-			if id == "" {
+		// 嵌入结构的字段提升到当前对象中
+		for _, field := range utils.StructFields(p.RealType) {
+			if field.Name == "_" && utils.HasGqlFlag(&field, "flatten") {
+				// 字段直接作为参数的标记
+				obj.Flatten = true
 				continue
 			}
+			id := objm.typeManager.FieldName(&field)
+			if id == "" || utils.HasGqlFlag(&field, "outputonly") {
+				// 忽略的字段或者只用于输出的字段
+				continue
+			}
+			prop := utils.ParseTypeProp(field.Type)
 			utils.ParseValueCheckers(prop, &field)
 			typeField := new(graphql.InputObjectFieldConfig)
-			ftype, isStruct := utils.StructFieldTypeToGraphType(&field)
+			ftype, isStruct := utils.StructFieldTypeToGraphType(&field, objm.typeManager.FindType)
 			fd := &Field{
 				Name:     field.Name,
 				JSONName: id,
+				Index:    field.Index,
 				Prop:     prop,
 			}
 			//graphql.Input()
 			typeField.Type = ftype
 			typeField.Description = prop.Desc
+			if typeField.Description == "" {
+				// 字段的文档注释
+				typeField.Description = fieldDoc(p.RealType, &field)
+			}
 
+			if IsKeyValueMap(&field) {
+				// map 以 [{key, value}] 列表的形式提交
+				kv := objm.typeManager.KeyValueType(utils.BaseType(field.Type, objm.typeManager.FindType), true)
+				typeField.Type = utils.WrapFieldType(&field, kv, objm.typeManager.FindType)
+			}
+			if str := utils.ParseGqlTagValue(&field, "default"); str != "" {
+				// 有默认值的字段可以不提交
+				fd.DefaultValue = objm.parseDefaultValue(&field, str)
+				typeField.DefaultValue = fd.DefaultValue
+				typeField.Type = utils.Nullable(typeField.Type)
+			}
+			if isStruct && prop.Kind == reflect.Interface {
+				panic(fmt.Errorf("Interface field %s is not supported in input object", field.Name))
+			}
 			if isStruct {
 				// 是结构类型，递归生成
-				typeField.Type = objm.FindOrRegisterObject(fd, id).Object
+				baseField := &Field{
+					Name:     field.Name,
+					JSONName: id,
+					Prop:     utils.ParseTypeProp(utils.BaseType(field.Type, objm.typeManager.FindType)),
+				}
+				// 与参数结构相同，使用结构名称命名
+				base := objm.FindOrRegisterObject(baseField, baseField.Prop.TypeName).Object
+				typeField.Type = utils.WrapFieldType(&field, base, objm.typeManager.FindType)
 			}
 
 			//if prop.IsList {
@@ -134,3 +178,36 @@ func (objm *RequestObjectManager) FindOrRegisterObject(field *Field, name string
 	}
 	return obj
 }
+
+// parseDefaultValue 解析字段的默认值，枚举使用枚举值的名称，自定义标量使用其 ParseValue 能够解析的字符串
+func (objm *RequestObjectManager) parseDefaultValue(field *reflect.StructField, str string) interface{} {
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	var val interface{}
+	var err error
+	if custom, ok := objm.typeManager.FindType(typ).(interface {
+		ParseValue(value interface{}) interface{}
+	}); ok && typ.Kind() != reflect.Map {
+		// 枚举或自定义标量
+		if val = custom.ParseValue(str); val == nil {
+			err = fmt.Errorf("无法解析 %s", str)
+		}
+		if _, ok := custom.(*graphql.Enum); ok {
+			// 内省中输出枚举值的名称
+			useEnumDefaultName()
+		}
+	} else {
+		val, err = utils.ParseDefaultValue(str, typ)
+	}
+	if err == nil {
+		// 检查是否可以赋值给字段，如数值溢出
+		_, err = utils.ConvertValue(val, field.Type)
+	}
+	if err != nil {
+		panic(fmt.Errorf("Default value of field %s is invalid: %s", field.Name, err.Error()))
+	}
+	return val
+}
@@ -16,6 +16,7 @@ import (
 // Resolver 查询和操作函数定义
 type Resolver struct {
 	manager *ResolverManager
+	info    *RegisterInfo // 注册信息，用于中间件
 	// gql                    *GQL
 	structInstance interface{} // 结构内方法
 	out            *Field
@@ -24,10 +25,29 @@ type Resolver struct {
 	executor       reflect.Value
 	inputCheckFn   ValidatorFn    // 输入参数检查函数
 	input          *RequestObject // 输入参数
+	inputList      reflect.Type   // 输入参数是结构数组时，数组的类型
 	//isGraphQLParamInParams bool           // params 是否包含 GraphSQL resolve 参数
 	//isValidatorInParams    bool           // params 是否包含 InputValidator
 	describe        string       // 描述信息
+	deprecation     string       // 废弃原因
 	funcInputParams []inputParam // 函数输入参数
+	flattenArgs     bool         // 输入结构的字段直接作为参数
+}
+
+// ResolverOptions 注册 Query、Mutation 和 Subscription 时的选项
+type ResolverOptions struct {
+	ValidateFn ValidatorFn // 输入参数验证函数
+	// FlattenArgs 输入结构的字段直接作为参数，如 goods(id: "100")
+	// 也可以在输入结构中使用 _ struct{} `gql:"flatten"` 标记
+	FlattenArgs bool
+	// Name 指定名称，不使用函数名称，只在注册单个函数时有效
+	Name string
+	// Description 描述信息，指定后不再使用 XxxDesc 方法
+	Description string
+	// DeprecationReason 不为空时，标记为已废弃
+	DeprecationReason string
+	// Prefix 名称前缀，原样加在名称之前，如 shop_，用于区分不同模块的同名函数
+	Prefix string
 }
 
 // ResolverManager 管理器
@@ -37,6 +57,9 @@ type ResolverManager struct {
 	reqObjManager *RequestObjectManager
 	inject        *Inject
 	resolverMap   map[string]*Resolver
+	names         []string // 按注册顺序排列的名称
+	middlewares   []Middleware
+	infos         []*RegisterInfo // 不通过 RegisterResolver 注册的函数，如结构字段方法
 }
 
 // RegisterInfo 注册状态
@@ -45,6 +68,7 @@ type RegisterInfo struct {
 	Package string
 	Struct  string
 	Func    string
+	Name    string // graphql 中的名称
 	Error   string
 }
 
@@ -77,17 +101,30 @@ func NewMutationResolverManager(inject *Inject,
 	}
 }
 
+// NewFieldResolverManager 创建结构字段方法管理器
+// 返回结构的方法（返回值为两个，且第二个是 error）将作为该结构对象的字段
+func NewFieldResolverManager(inject *Inject,
+	responseObjectManager *ResponseObjectManager,
+	requestObjectManager *RequestObjectManager) *ResolverManager {
+	return &ResolverManager{
+		name:          "Field",
+		inject:        inject,
+		resObjManager: responseObjectManager,
+		reqObjManager: requestObjectManager,
+		resolverMap:   make(map[string]*Resolver),
+	}
+}
+
 // CreateResolveObject 创建查询对象结构
 func (rm *ResolverManager) CreateResolveObject() *graphql.Object {
 	fields := graphql.Fields{}
 
-	has := false
-	for name, r := range rm.resolverMap {
-		has = true
-		fields[name] = r.CreateField()
+	// 按注册顺序创建字段，类型名称冲突时总是后注册的类型被重新命名
+	for _, name := range rm.names {
+		fields[name] = rm.resolverMap[name].CreateField()
 	}
 
-	if !has {
+	if len(fields) == 0 {
 		return nil
 	}
 
@@ -104,25 +141,30 @@ func (rm *ResolverManager) RegisterResolver(fn *def.FuncInfo,
 	//funcName string,
 	//funcType reflect.Type,
 	//function reflect.Value,
-	validateFn ValidatorFn) *RegisterInfo {
+	opts *ResolverOptions) *RegisterInfo {
 
 	structName := fn.GetStructName()
-
-	_, ok := rm.resolverMap[fn.Name]
-	if ok {
-		panic(fmt.Errorf("Mutation Resolve [%s] exists", fn.Name))
-	}
+	name := rm.ResolverName(fn, opts)
 
 	info := &RegisterInfo{
 		Type:    rm.name,
 		Package: fn.Pkg,
 		Struct:  structName,
 		Func:    fn.Name,
+		Name:    name,
+	}
+
+	if _, ok := rm.resolverMap[name]; ok {
+		// 名称相同时，舍弃后面的函数
+		info.Error = fmt.Sprintf("%s [%s] 已经存在", rm.name, name)
+		return info
 	}
 
-	r, err := rm.TryParseResolver(fn, validateFn)
+	r, err := rm.TryParseResolver(fn, opts)
 	if err == nil {
-		rm.resolverMap[fn.Name] = r
+		r.info = info
+		rm.resolverMap[name] = r
+		rm.names = append(rm.names, name)
 	} else {
 		// 注册失败
 		if !strings.HasSuffix(fn.Name, "Desc") {
@@ -137,19 +179,57 @@ func (rm *ResolverManager) RegisterResolver(fn *def.FuncInfo,
 	return info
 }
 
+// Infos 不通过 RegisterResolver 注册的函数的注册信息，如结构字段方法，生成 schema 之后才有
+func (rm *ResolverManager) Infos() []*RegisterInfo {
+	return rm.infos
+}
+
+// ResolverName 函数在 graphql 中的名称
+// 优先使用选项中指定的名称，否则按照命名策略转换函数名称，最后加上选项中的前缀
+func (rm *ResolverManager) ResolverName(fn *def.FuncInfo, opts *ResolverOptions) string {
+	if opts == nil {
+		return rm.resObjManager.typeManager.ResolverName(fn.Name)
+	}
+	name := opts.Name
+	if name == "" || fn.Struct != nil {
+		// 结构中的多个方法不能使用同一个名称
+		name = rm.resObjManager.typeManager.ResolverName(fn.Name)
+	}
+	return opts.Prefix + name
+}
+
 // TryParseResolver 尝试把函数解析为 Resolver
 func (rm *ResolverManager) TryParseResolver(fn *def.FuncInfo,
 	//functionType reflect.Type,
 	//function reflect.Value,
 	//structInstance interface{},
-	inputCheckFn ValidatorFn) (*Resolver, error) {
+	opts *ResolverOptions) (*Resolver, error) {
+	if opts == nil {
+		opts = &ResolverOptions{}
+	}
 	// method := reflect.TypeOf(function)
 	res := &Resolver{
 		manager:        rm,
 		structInstance: fn.Struct,
 		executor:       fn.Func,
-		inputCheckFn:   inputCheckFn,
-		describe:       fn.GetDescribe(),
+		inputCheckFn:   opts.ValidateFn,
+		describe:       opts.Description,
+		deprecation:    opts.DeprecationReason,
+		flattenArgs:    opts.FlattenArgs,
+		info: &RegisterInfo{
+			Type:    rm.name,
+			Package: fn.Pkg,
+			Struct:  fn.GetStructName(),
+			Func:    fn.Name,
+			Name:    rm.ResolverName(fn, opts),
+		},
+	}
+	if res.describe == "" {
+		res.describe = fn.GetDescribe()
+	}
+	if res.describe == "" {
+		// 函数的文档注释
+		res.describe = funcDoc(fn)
 	}
 
 	// 函数返回值必须是两个，建议为两个：(其他类型, error)
@@ -162,11 +242,23 @@ func (rm *ResolverManager) TryParseResolver(fn *def.FuncInfo,
 	for n := 0; n < outCount; n++ {
 		outParam := fn.Type.Out(n)
 		prop := utils.ParseTypeProp(outParam)
+		if n == 0 && rm.isSubscription() {
+			// 订阅函数返回 channel，事件的类型作为返回类型
+			if outParam.Kind() != reflect.Chan || outParam.ChanDir()&reflect.RecvDir == 0 {
+				return nil, errors.New("订阅函数的第一个返回值必须是可以接收的 channel 类型")
+			}
+			prop = utils.ParseTypeProp(outParam.Elem())
+		}
 		if n == 0 {
 			// 第一个参数
 			res.out = &Field{
 				Prop: prop,
 			}
+			if prop.Kind == reflect.Interface &&
+				rm.resObjManager.typeManager.FindAbstract(prop.RealType) == nil {
+				// 返回接口类型时，接口必须已经注册
+				return nil, errors.New("函数返回的 interface 类型必须先注册")
+			}
 			// fmt.Println(res.out)
 		} else if n == 1 {
 			// 第二个参数 error
@@ -181,8 +273,15 @@ func (rm *ResolverManager) TryParseResolver(fn *def.FuncInfo,
 		}
 	}
 
+	// 返回值必须可以转换为 graphql 类型
+	finder := rm.resObjManager.typeManager.FindType
+	if _, _, err := utils.TypeToGraphQLType(utils.BaseType(res.out.Prop.SrcType, finder), finder); err != nil {
+		return nil, err
+	}
+
 	// 解析输入参数
 	// 输入参数可以使 0~3 个，函数属于结构体，则额外多出一个参数
+	// 可以包含 context.Context 参数，即请求的 context
 	// 至多包含一个 graphql.ResolveParams 参数
 	// 至多包含一个 InputValidator 类型的指针参数
 	// 至多包含一个 自定义 struct 类型的指针参数
@@ -198,7 +297,10 @@ func (rm *ResolverManager) TryParseResolver(fn *def.FuncInfo,
 			Prop: prop,
 		}
 
-		if n == 0 && fn.Struct != nil {
+		if n == 0 && fn.Receiver != nil {
+			// 结构字段方法的接收者
+			res.funcInputParams[n] = &ipSource{typ: fn.Receiver}
+		} else if n == 0 && fn.Struct != nil {
 			res.funcInputParams[n] = &ipStruct{}
 			// 结构体参数
 		} else if prop.RealType == typeOfResolveParams {
@@ -215,6 +317,9 @@ func (rm *ResolverManager) TryParseResolver(fn *def.FuncInfo,
 			}
 			res.funcInputParams[n] = &ipValidator{}
 			//res.isValidatorInParams = true
+		} else if prop.RealType == typeOfContext && !prop.IsPtr {
+			// 请求的 context
+			res.funcInputParams[n] = &ipContext{}
 		} else if prop.Kind == reflect.Interface {
 			// 接口，必须是注入类型
 			inject := rm.inject.FindInject(prop.RealType)
@@ -226,7 +331,7 @@ func (rm *ResolverManager) TryParseResolver(fn *def.FuncInfo,
 				inject:           inject,
 			}
 		} else if prop.Kind == reflect.Struct {
-			if !prop.IsPtr {
+			if !prop.IsPtr && !prop.IsList {
 				// 必须是指针类型
 				return nil, errors.New("函数接收输入的 struct 参数必须是指针类型")
 			}
@@ -241,13 +346,23 @@ func (rm *ResolverManager) TryParseResolver(fn *def.FuncInfo,
 				}
 
 				// 结构参数，也许可用
-				res.input = rm.reqObjManager.FindOrRegisterObject(p, prop.TypeName)
+				if prop.IsList {
+					// 结构数组，用于批量提交
+					res.inputList = prop.SrcType
+					baseProp := utils.ParseTypeProp(utils.BaseType(prop.SrcType, nil))
+					res.input = rm.reqObjManager.FindOrRegisterObject(&Field{Prop: baseProp}, prop.TypeName)
+				} else {
+					res.input = rm.reqObjManager.FindOrRegisterObject(p, prop.TypeName)
+				}
 				if res.input == nil {
 					// 不支持的结构
 					return nil, errors.New("函数接收数据的 struct 的字段只能是 string, int, float, time, bool 类型")
 				}
 				// 是结构类型请求参数
 				res.funcInputParams[n] = &ipRequest{}
+				if res.input.Flatten {
+					res.flattenArgs = true
+				}
 			} else { // 是注入类型
 				res.funcInputParams[n] = &ipInject{
 					valueIsInterface: false,
@@ -256,7 +371,7 @@ func (rm *ResolverManager) TryParseResolver(fn *def.FuncInfo,
 			}
 		} else {
 			// 不支持的数据类型
-			return nil, errors.New("函数只接受 gql.InputValidator、graphql.ResolveParams 和自定义结构类型的参数")
+			return nil, errors.New("函数只接受 context.Context、gql.InputValidator、graphql.ResolveParams 和自定义结构类型的参数")
 		}
 
 		res.params[n] = p //  append(res.params, p)
@@ -268,31 +383,51 @@ func (rm *ResolverManager) TryParseResolver(fn *def.FuncInfo,
 // CreateField 创建操作对象
 func (r *Resolver) CreateField() *graphql.Field {
 	var field = graphql.Field{
-		Description: r.describe,
+		Description:       r.describe,
+		DeprecationReason: r.deprecation,
 	}
 
 	// 解析返回参数，注册 object
-	if r.out.Prop.IsPrimitive {
-		var err error
-		field.Type, _, err = utils.TypeToGraphQLType(r.out.Prop.RealType)
-		if r.out.Prop.IsList {
-			field.Type = graphql.NewList(field.Type)
-		}
-		if err != nil {
-			panic(err)
-		}
-	} else {
+	finder := r.manager.resObjManager.typeManager.FindType
+	baseType := utils.BaseType(r.out.Prop.SrcType, finder)
+	base, isStruct, err := utils.TypeToGraphQLType(baseType, finder)
+	if err != nil {
+		panic(err)
+	}
+	if isStruct {
 		// 不是原生类型
-		qobj := r.manager.resObjManager.FindOrRegisterObject(r.out)
-		field.Type = qobj.Object
+		qobj := r.manager.resObjManager.FindOrRegisterObject(&Field{Prop: utils.ParseTypeProp(baseType)})
+		base = qobj.Object
 	}
+	// 返回错误时返回值为空，所以返回值本身总是可以为空
+	field.Type = utils.Nullable(utils.WrapType(r.out.Prop.SrcType, baseType, base))
 
 	if r.input != nil {
 		iType := r.manager.reqObjManager.FindOrRegisterObject(r.input.Param, "")
-		field.Args = graphql.FieldConfigArgument{
-			r.input.Name: &graphql.ArgumentConfig{
-				Type: iType.Object,
-			},
+		if r.inputList != nil {
+			// 结构数组
+			field.Args = graphql.FieldConfigArgument{
+				r.input.Name: &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(utils.WrapType(r.inputList, r.input.Param.Prop.RealType, iType.Object)),
+				},
+			}
+		} else if r.flattenArgs {
+			// 输入结构的字段直接作为参数
+			field.Args = graphql.FieldConfigArgument{}
+			for name, f := range iType.FieldMap {
+				field.Args[name] = &graphql.ArgumentConfig{
+					Type:         f.Type,
+					DefaultValue: f.DefaultValue,
+					Description:  f.Description,
+				}
+			}
+		} else {
+			field.Args = graphql.FieldConfigArgument{
+				r.input.Name: &graphql.ArgumentConfig{
+					// 输入参数是必须的，见 InputValidator.ParseInput
+					Type: graphql.NewNonNull(iType.Object),
+				},
+			}
 		}
 	}
 
@@ -303,13 +438,18 @@ func (r *Resolver) CreateField() *graphql.Field {
 			validatorFn:          r.inputCheckFn,
 			graphqlParam:         p,
 			requestObjectManager: r.manager.reqObjManager,
+			flattenArgs:          r.flattenArgs,
 		}
 		var input reflect.Value
 		// 构建参数
 		if r.input != nil {
 			// 生成输入数据结构
 			var err error
-			input, err = validator.ParseInput(&p, r.input)
+			if r.inputList != nil {
+				input, err = validator.ParseInputList(&p, r.input, r.inputList)
+			} else {
+				input, err = validator.ParseInput(&p, r.input)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -397,5 +537,18 @@ func (r *Resolver) CreateField() *graphql.Field {
 		return out, err
 	}
 
+	// 中间件只包装函数的调用
+	field.Resolve = r.resolveWithMiddlewares(field.Resolve)
+	// panic 转换为错误
+	field.Resolve = r.manager.resObjManager.resolveWithRecover(field.Resolve)
+	if r.manager.isSubscription() {
+		// 订阅，通过 Subscribe 执行
+		field.Resolve = resolveSubscription(field.Resolve)
+	}
+	if r.deprecation != "" {
+		// 已废弃，使用时报告
+		field.Resolve = r.manager.resObjManager.resolveDeprecated(r.deprecation, field.Resolve)
+	}
+
 	return &field
 }
@@ -1,7 +1,12 @@
 package gqlh
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+
 	"github.com/graphql-go/graphql"
+	"github.com/seerx/gql/pkg/def"
 	"github.com/seerx/gql/pkg/utils"
 )
 
@@ -13,13 +18,22 @@ type ResponseObject struct {
 
 // ResponseObjectManager  请求对象管理
 type ResponseObjectManager struct {
-	objectMap map[string]*ResponseObject
+	objectMap   map[string]*ResponseObject
+	typeManager *TypeManager
+	types       []graphql.Type // 需要额外加入 schema 的类型
+	// 结构字段方法管理器，为 nil 时不解析结构的方法
+	fieldResolverManager *ResolverManager
+	// 使用已废弃字段时的回调函数
+	deprecatedUsageFn DeprecatedUsageFn
+	// 记录未知 panic 的函数
+	panicLogger PanicLogger
 }
 
 // NewResponseObjectManager 创建管理器
-func NewResponseObjectManager() *ResponseObjectManager {
+func NewResponseObjectManager(typeManager *TypeManager) *ResponseObjectManager {
 	return &ResponseObjectManager{
-		objectMap: make(map[string]*ResponseObject),
+		objectMap:   make(map[string]*ResponseObject),
+		typeManager: typeManager,
 	}
 }
 
@@ -46,10 +60,28 @@ func (objm *ResponseObjectManager) FindOrRegisterObject(field *Field) *ResponseO
 		obj, ok = objm.objectMap[key]
 	}
 	if !ok {
+		if custom := objm.typeManager.FindType(field.Prop.RealType); custom != nil {
+			// 自定义类型（枚举等）
+			obj = objm.registerObject(field, custom)
+			if list {
+				obj = objm.registerList(field, graphql.NewList(custom))
+			}
+			return obj
+		}
+
+		if field.Prop.Kind == reflect.Interface {
+			// 接口，生成 graphql interface 或 union
+			obj = objm.registerAbstract(field)
+			if list {
+				obj = objm.registerList(field, graphql.NewList(obj.Object))
+			}
+			return obj
+		}
+
 		// 没有找到，
 		// 注册
 		p := field.Prop
-		name := p.TypeName
+		name := objm.typeManager.TypeName(p.RealType)
 
 		//if p.IsPrimitive {
 		//	// 原生类型
@@ -68,8 +100,10 @@ func (objm *ResponseObjectManager) FindOrRegisterObject(field *Field) *ResponseO
 		objFields := graphql.Fields{}
 		// 注册单个查询对象
 		gobj := graphql.NewObject(graphql.ObjectConfig{
-			Name:   name,
-			Fields: objFields,
+			Name:        name,
+			Description: typeDoc(p.RealType),
+			Fields:      objFields,
+			Interfaces:  objm.interfacesThunk(p.RealType),
 		})
 		obj = objm.registerObject(field, gobj)
 		if list {
@@ -78,37 +112,287 @@ func (objm *ResponseObjectManager) FindOrRegisterObject(field *Field) *ResponseO
 		}
 
 		// typeField := new(graphql.Field)
-		for n := 0; n < p.RealType.NumField(); n++ {
-			field := p.RealType.Field(n)
-			id := utils.ParseStructFieldName(&field)
-
+		// 嵌入结构的字段提升到当前对象中
+		for _, field := range utils.StructFields(p.RealType) {
+			id, typeField := objm.createField(p.RealType, &field)
 			if id == "" {
 				continue
 			}
+			objFields[id] = typeField
+		}
+
+		// 结构的方法作为字段
+		objm.registerMethodFields(p.RealType, objFields)
 
-			typeField := new(graphql.Field)
-			ftype, isStruct := utils.StructFieldTypeToGraphType(&field)
-			if isStruct {
-				structFieldProp := utils.ParseTypeProp(field.Type)
-				structFieldParam := &Field{Prop: structFieldProp}
-				// 递归创建下属对象
-				fobj := objm.FindOrRegisterObject(structFieldParam)
-				typeField.Type = fobj.Object
-			} else {
-				typeField.Type = ftype
+		// 确保该结构实现的接口已经生成
+		for _, abs := range objm.typeManager.InterfacesOf(p.RealType) {
+			objm.FindOrRegisterObject(&Field{Prop: utils.ParseTypeProp(abs.Type)})
+		}
+	}
+	return obj
+}
+
+// SetFieldResolverManager 设置结构字段方法管理器
+func (objm *ResponseObjectManager) SetFieldResolverManager(manager *ResolverManager) {
+	objm.fieldResolverManager = manager
+}
+
+// registerMethodFields 把结构中 _ struct{} `gql:"methods=Price|Total"` 标记列出的方法注册为字段
+// 方法的返回值必须是两个，且第二个是 error，参数规则与 Query 和 Mutation 函数相同
+// 没有列出的方法不会暴露给客户端，不符合规则的方法记录在注册信息中
+func (objm *ResponseObjectManager) registerMethodFields(typ reflect.Type, objFields graphql.Fields) {
+	if objm.fieldResolverManager == nil {
+		return
+	}
+	ptrType := reflect.PtrTo(typ)
+	for _, methodName := range methodFieldNames(typ) {
+		info := &RegisterInfo{
+			Type:    objm.fieldResolverManager.name,
+			Package: typ.PkgPath(),
+			Struct:  typ.Name(),
+			Func:    methodName,
+			Name:    objm.typeManager.ResolverName(methodName),
+		}
+		objm.fieldResolverManager.infos = append(objm.fieldResolverManager.infos, info)
+
+		method, ok := ptrType.MethodByName(methodName)
+		if !ok {
+			info.Error = fmt.Sprintf("结构 %s 没有方法 %s", typ.Name(), methodName)
+			continue
+		}
+		if _, ok := objFields[info.Name]; ok {
+			info.Error = fmt.Sprintf("%s [%s] 已经存在", typ.Name(), info.Name)
+			continue
+		}
+		fn := &def.FuncInfo{
+			Pkg:      typ.PkgPath(),
+			Name:     method.Name,
+			Type:     method.Type,
+			Func:     method.Func,
+			Receiver: ptrType,
+		}
+		r, err := objm.fieldResolverManager.TryParseResolver(fn, nil)
+		if err != nil {
+			info.Error = err.Error()
+			continue
+		}
+		r.info = info
+		objFields[info.Name] = r.CreateField()
+	}
+}
+
+// methodFieldNames 结构中 _ struct{} `gql:"methods=Price|Total"` 标记列出的方法名称
+func methodFieldNames(typ reflect.Type) []string {
+	var names []string
+	for n := 0; n < typ.NumField(); n++ {
+		field := typ.Field(n)
+		if field.Name != "_" {
+			continue
+		}
+		for _, name := range strings.Split(utils.ParseGqlTagValue(&field, "methods"), "|") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
 			}
+		}
+	}
+	return names
+}
 
-			//utils.ParseValueCheckers(prop, &field)
-			desc := utils.ParseFieldDesc(&field)
-			typeField.Description = desc
+// createField 根据结构字段生成 graphql 字段，返回的名称为空时表示忽略该字段
+// @param parent 字段所在的结构类型
+func (objm *ResponseObjectManager) createField(parent reflect.Type, field *reflect.StructField) (string, *graphql.Field) {
+	id := objm.typeManager.FieldName(field)
+	if id == "" || utils.HasGqlFlag(field, "inputonly") {
+		// 忽略的字段或者只用于输入的字段
+		return "", nil
+	}
 
-			objFields[id] = typeField
+	typeField := new(graphql.Field)
+	ftype, isStruct := utils.StructFieldTypeToGraphType(field, objm.typeManager.FindType)
+	if needResolveByIndex(field, id) {
+		// 嵌入结构中的字段或者改名的字段，默认的 resolve 函数无法获取
+		typeField.Resolve = resolveByIndex(field.Index)
+	}
+	if IsKeyValueMap(field) {
+		// map 以 [{key, value}] 列表的形式输出
+		kv := objm.typeManager.KeyValueType(utils.BaseType(field.Type, objm.typeManager.FindType), false)
+		typeField.Type = utils.WrapFieldType(field, kv, objm.typeManager.FindType)
+		typeField.Resolve = resolveKeyValue(typeField.Resolve)
+	} else if isStruct {
+		structFieldProp := utils.ParseTypeProp(utils.BaseType(field.Type, objm.typeManager.FindType))
+		structFieldParam := &Field{Prop: structFieldProp}
+		// 递归创建下属对象
+		fobj := objm.FindOrRegisterObject(structFieldParam)
+		typeField.Type = utils.WrapFieldType(field, fobj.Object, objm.typeManager.FindType)
+	} else {
+		typeField.Type = ftype
+	}
+
+	if utils.IsEmbeddedByPointer(parent, field.Index) {
+		// 嵌入的结构指针可能为 nil
+		typeField.Type = utils.Nullable(typeField.Type)
+	}
+
+	//utils.ParseValueCheckers(prop, &field)
+	desc := utils.ParseFieldDesc(field)
+	if desc == "" {
+		// 字段的文档注释
+		desc = fieldDoc(parent, field)
+	}
+	typeField.Description = desc
+
+	if reason := utils.ParseGqlTagValue(field, "deprecated"); reason != "" {
+		// 已废弃的字段
+		typeField.DeprecationReason = reason
+		typeField.Resolve = objm.resolveDeprecated(reason, typeField.Resolve)
+	}
+	return id, typeField
+}
+
+// needResolveByIndex 是否需要根据索引路径获取字段值
+// graphql 默认的 resolve 函数只能根据字段名称或者 json tag 查找当前结构中的字段
+func needResolveByIndex(field *reflect.StructField, id string) bool {
+	if len(field.Index) > 1 {
+		return true
+	}
+	return !strings.EqualFold(field.Name, id) && strings.Split(field.Tag.Get("json"), ",")[0] != id
+}
+
+// resolveByIndex 根据索引路径获取字段值，嵌入的结构指针为 nil 时返回 nil
+func resolveByIndex(index []int) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		val := reflect.ValueOf(p.Source)
+		for _, x := range index {
+			for val.Kind() == reflect.Ptr {
+				if val.IsNil() {
+					return nil, nil
+				}
+				val = val.Elem()
+			}
+			if val.Kind() != reflect.Struct {
+				return nil, nil
+			}
+			val = val.Field(x)
+		}
+		return val.Interface(), nil
+	}
+}
+
+// registerAbstract 注册接口类型，生成 graphql interface 或 union
+func (objm *ResponseObjectManager) registerAbstract(field *Field) *ResponseObject {
+	p := field.Prop
+	abs := objm.typeManager.FindAbstract(p.RealType)
+	if abs == nil {
+		panic(fmt.Errorf("Interface [%s] is not registered", p.TypeName))
+	}
+
+	resolveType := func(rp graphql.ResolveTypeParams) *graphql.Object {
+		return objm.findObjectOfValue(rp.Value)
+	}
+
+	if abs.IsUnion {
+		// union 需要先生成所有的实现对象
+		var types []*graphql.Object
+		for _, impl := range abs.Impls {
+			types = append(types, objm.registerImpl(impl))
+		}
+		if obj, ok := objm.objectMap[p.Key()]; ok {
+			// 生成实现对象时，已经递归注册
+			return obj
 		}
+		return objm.registerObject(field, graphql.NewUnion(graphql.UnionConfig{
+			Name:        objm.typeManager.TypeName(p.RealType),
+			Description: typeDoc(p.RealType),
+			Types:       types,
+			ResolveType: resolveType,
+		}))
+	}
+
+	objFields := graphql.Fields{}
+	obj := objm.registerObject(field, graphql.NewInterface(graphql.InterfaceConfig{
+		Name:        objm.typeManager.TypeName(p.RealType),
+		Description: typeDoc(p.RealType),
+		Fields:      objFields,
+		ResolveType: resolveType,
+	}))
+
+	// 接口的字段是所有实现结构共有的字段（名称和类型都相同）
+	for _, field := range utils.StructFields(abs.Impls[0]) {
+		id, typeField := objm.createField(abs.Impls[0], &field)
+		if id == "" || !objm.isCommonField(abs.Impls[1:], id, field.Type) {
+			continue
+		}
+		objFields[id] = typeField
+	}
+
+	for _, impl := range abs.Impls {
+		objm.registerImpl(impl)
+	}
+	return obj
+}
 
+// registerImpl 注册接口的实现结构，实现结构需要加入到 schema 的类型列表中
+func (objm *ResponseObjectManager) registerImpl(impl reflect.Type) *graphql.Object {
+	prop := utils.ParseTypeProp(impl)
+	obj := objm.FindOrRegisterObject(&Field{Prop: prop}).Object.(*graphql.Object)
+	for _, t := range objm.types {
+		if t == obj {
+			return obj
+		}
 	}
+	objm.types = append(objm.types, obj)
 	return obj
 }
 
+// interfacesThunk 结构实现的 graphql 接口列表，延迟到生成 schema 时获取
+func (objm *ResponseObjectManager) interfacesThunk(typ reflect.Type) graphql.InterfacesThunk {
+	return func() []*graphql.Interface {
+		var list []*graphql.Interface
+		for _, abs := range objm.typeManager.InterfacesOf(typ) {
+			if obj, ok := objm.objectMap[utils.ParseTypeProp(abs.Type).Key()]; ok {
+				list = append(list, obj.Object.(*graphql.Interface))
+			}
+		}
+		return list
+	}
+}
+
+// findObjectOfValue 根据值的实际类型查找 graphql 对象
+func (objm *ResponseObjectManager) findObjectOfValue(value interface{}) *graphql.Object {
+	if value == nil {
+		return nil
+	}
+	prop := utils.ParseTypeProp(reflect.TypeOf(value))
+	if obj, ok := objm.objectMap[prop.Key()]; ok {
+		if gobj, ok := obj.Object.(*graphql.Object); ok {
+			return gobj
+		}
+	}
+	return nil
+}
+
+// Types 需要额外加入到 schema 中的类型，如接口的实现对象
+func (objm *ResponseObjectManager) Types() []graphql.Type {
+	return objm.types
+}
+
+// isCommonField 判断所有结构中是否都有名称和类型相同的字段
+func (objm *ResponseObjectManager) isCommonField(types []reflect.Type, id string, typ reflect.Type) bool {
+	for _, t := range types {
+		found := false
+		for _, field := range utils.StructFields(t) {
+			if objm.typeManager.FieldName(&field) == id && field.Type == typ {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 func (objm *ResponseObjectManager) registerObject(param *Field, obj graphql.Output) *ResponseObject {
 	qobj := &ResponseObject{
 		Object: obj,
@@ -0,0 +1,120 @@
+package gqlh
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// ScalarConfig 自定义标量定义
+type ScalarConfig struct {
+	Name        string // graphql 类型名称
+	Description string // 描述信息
+	// Serialize 把 go 值转换为输出值，如 decimal.Decimal 转换为 string
+	Serialize func(value interface{}) interface{}
+	// ParseValue 把输入值（string, int, float64, bool 等）转换为 go 值，无法转换时返回 nil
+	ParseValue func(value interface{}) interface{}
+}
+
+// Base64Scalar []byte 以 Base64 字符串的形式输入输出
+// g.RegisterScalar([]byte{}, gqlh.Base64Scalar)
+var Base64Scalar = &ScalarConfig{
+	Name:        "Base64",
+	Description: "Base64 encoded bytes",
+	Serialize: func(value interface{}) interface{} {
+		data, ok := value.([]byte)
+		if !ok {
+			return nil
+		}
+		return base64.StdEncoding.EncodeToString(data)
+	},
+	ParseValue: func(value interface{}) interface{} {
+		str, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		data, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return nil
+		}
+		return data
+	},
+}
+
+var errOfScalar = errors.New("Param cfg of RegisterScalar must have Name, Serialize and ParseValue")
+
+// RegisterScalar 注册自定义标量类型
+// @param sample 该类型的一个值，例如 decimal.Decimal{}
+// @param cfg 标量定义
+func (tm *TypeManager) RegisterScalar(sample interface{}, cfg *ScalarConfig) {
+	typ := reflect.TypeOf(sample)
+	if typ == nil {
+		panic(errOfScalar)
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if cfg == nil || cfg.Name == "" || cfg.Serialize == nil || cfg.ParseValue == nil {
+		panic(errOfScalar)
+	}
+	if tm.IsEnum(typ) || tm.IsScalar(typ) {
+		panic(fmt.Errorf("Type [%s] is Registered", typ.String()))
+	}
+
+	serialize := func(value interface{}) interface{} {
+		val := reflect.ValueOf(value)
+		if val.Kind() == reflect.Ptr && val.Type().Elem() == typ {
+			// 指针类型
+			if val.IsNil() {
+				return nil
+			}
+			value = val.Elem().Interface()
+		}
+		return cfg.Serialize(value)
+	}
+
+	tm.reserveName(cfg.Name, typ)
+	tm.scalarMap[typ] = graphql.NewScalar(graphql.ScalarConfig{
+		Name:        cfg.Name,
+		Description: cfg.Description,
+		Serialize:   serialize,
+		ParseValue:  cfg.ParseValue,
+		ParseLiteral: func(valueAST ast.Value) interface{} {
+			value := literalValue(valueAST)
+			if value == nil {
+				return nil
+			}
+			return cfg.ParseValue(value)
+		},
+	})
+}
+
+// IsScalar 判断是否是已经注册的自定义标量类型
+func (tm *TypeManager) IsScalar(typ reflect.Type) bool {
+	_, ok := tm.scalarMap[typ]
+	return ok
+}
+
+// literalValue 把 graphql 语句中的字面值转换为 go 值
+func literalValue(valueAST ast.Value) interface{} {
+	switch v := valueAST.(type) {
+	case *ast.StringValue:
+		return v.Value
+	case *ast.IntValue:
+		if n, err := strconv.Atoi(v.Value); err == nil {
+			return n
+		}
+	case *ast.FloatValue:
+		if f, err := strconv.ParseFloat(v.Value, 64); err == nil {
+			return f
+		}
+	case *ast.BooleanValue:
+		return v.Value
+	}
+	return nil
+}
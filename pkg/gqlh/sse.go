@@ -0,0 +1,144 @@
+package gqlh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// SSE 事件类型
+const (
+	sseNext     = "next"
+	sseComplete = "complete"
+)
+
+// IsEventStreamRequest 是否是 SSE 请求，即 Accept 中包含 text/event-stream
+func IsEventStreamRequest(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// SSEHandler 通过 Server-Sent Events 执行订阅（GraphQL over SSE 协议的 distinct connections 模式）
+// 每个请求一个连接，每个结果发送一个 next 事件，结束时发送 complete 事件
+// 查询和操作也可以通过 SSE 执行，只发送一个 next 事件
+type SSEHandler struct {
+	cfg *SubscriptionConfig
+}
+
+// NewSSEHandler 创建 SSE 订阅处理器
+func NewSSEHandler(cfg *SubscriptionConfig) *SSEHandler {
+	return &SSEHandler{cfg: cfg}
+}
+
+// ServeHTTP 处理 SSE 请求，支持 GET（参数在 URL 中）和 POST（JSON）
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseSSERequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, errors.New("不支持 SSE"))
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 禁止 nginx 缓存响应
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// 客户端断开时 r.Context() 被取消，订阅随之结束
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	ctx, params := h.cfg.newSubscribeParams(ctx, r, opts)
+	results := h.cfg.Subscribe(ctx, params)
+
+	ticker := time.NewTicker(h.cfg.keepAlive())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// 心跳，注释行会被客户端忽略
+			if _, err := fmt.Fprint(w, ":\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case result, ok := <-results:
+			if !ok {
+				// 订阅结束
+				writeEvent(w, sseComplete, nil)
+				flusher.Flush()
+				return
+			}
+			data, err := json.Marshal(h.cfg.formatResult(result))
+			if err != nil {
+				return
+			}
+			if writeEvent(w, sseNext, data) != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// parseSSERequest 解析请求参数
+func parseSSERequest(r *http.Request) (*RequestOptions, error) {
+	opts := &RequestOptions{}
+	switch r.Method {
+	case http.MethodGet:
+		values := r.URL.Query()
+		opts.Query = values.Get("query")
+		opts.OperationName = values.Get("operationName")
+		if str := values.Get("variables"); str != "" {
+			if err := json.Unmarshal([]byte(str), &opts.Variables); err != nil {
+				return nil, fmt.Errorf("variables 格式不正确: %s", err.Error())
+			}
+		}
+		if str := values.Get("extensions"); str != "" {
+			if err := json.Unmarshal([]byte(str), &opts.Extensions); err != nil {
+				return nil, fmt.Errorf("extensions 格式不正确: %s", err.Error())
+			}
+		}
+	case http.MethodPost:
+		if r.Body == nil {
+			return nil, errors.New("没有请求参数")
+		}
+		if err := json.NewDecoder(r.Body).Decode(opts); err != nil {
+			return nil, fmt.Errorf("请求参数格式不正确: %s", err.Error())
+		}
+	default:
+		return nil, fmt.Errorf("不支持的请求方法 %s", r.Method)
+	}
+	if opts.Query == "" {
+		return nil, errors.New("没有 query 参数")
+	}
+	return opts, nil
+}
+
+// writeEvent 发送一个事件
+func writeEvent(w http.ResponseWriter, event string, data []byte) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// writeJSONError 以 {"errors": [...]} 的形式返回错误信息
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []gqlerrors.FormattedError{gqlerrors.FormatError(err)},
+	})
+}
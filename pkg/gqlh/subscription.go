@@ -0,0 +1,226 @@
+package gqlh
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+const keyOfSubscription = "subscription"
+
+// 没有通过 WebSocket 或 SSE 执行订阅
+var errOfSubscriptionTransport = NewError(CodeBadRequest, "订阅只能通过 WebSocket 或 SSE 执行")
+
+// 订阅请求只能有一个字段
+var errOfSubscriptionFields = NewError(CodeGraphQLValidationFailed, "订阅请求只能有一个字段")
+
+// SubscriptionConfig WebSocket 和 SSE 订阅处理器的配置
+type SubscriptionConfig struct {
+	Schema *graphql.Schema
+	// RootObjectFn 生成 root value，与 handler.Config 中的相同
+	RootObjectFn func(ctx context.Context, r *http.Request) map[string]interface{}
+	// FormatErrorFn 格式化错误信息，与 handler.Config 中的相同
+	FormatErrorFn func(err error) gqlerrors.FormattedError
+	// KeepAlive 心跳间隔，为 0 时使用 DefaultKeepAlive
+	KeepAlive time.Duration
+	// ResponseObjectManager 订阅过程中的未知 panic 使用其 SetPanicLogger 设置的函数记录（每次 panic 时获取），
+	// 为 nil 时使用默认的日志
+	ResponseObjectManager *ResponseObjectManager
+}
+
+// DefaultKeepAlive 默认的心跳间隔
+const DefaultKeepAlive = 15 * time.Second
+
+// RequestOptions 订阅请求的参数
+type RequestOptions struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// subscription 一次订阅的状态，存储在 root value 中
+// 第一次执行时调用订阅函数，获取 channel；之后每收到一个事件执行一次，订阅字段直接返回该事件
+type subscription struct {
+	ctx     context.Context // 订阅的 context，客户端取消订阅时被取消
+	source  reflect.Value   // 订阅函数返回的 channel
+	started bool            // 是否已经开始接收事件
+	event   interface{}     // 当前事件
+}
+
+// detachedContext 保留 context 中的值，但不会被取消
+// graphql.Do 在 context 被取消时直接返回，执行中的 goroutine 仍然会写入结果，造成数据竞争；
+// 订阅通过 detachedContext 执行，取消时等待本次执行结束
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// NewSubscriptionResolverManager 创建订阅管理器
+// 订阅函数的第一个返回值必须是 channel，如 func(...) (<-chan *Order, error)
+func NewSubscriptionResolverManager(inject *Inject,
+	responseObjectManager *ResponseObjectManager,
+	requestObjectManager *RequestObjectManager) *ResolverManager {
+	return &ResolverManager{
+		name:          "Subscription",
+		inject:        inject,
+		resObjManager: responseObjectManager,
+		reqObjManager: requestObjectManager,
+		resolverMap:   make(map[string]*Resolver),
+	}
+}
+
+// isSubscription 是否是订阅管理器
+func (rm *ResolverManager) isSubscription() bool {
+	return rm.name == "Subscription"
+}
+
+// resolveSubscription 包装订阅字段的 resolve 函数
+// 第一次执行时调用订阅函数并保存返回的 channel，之后直接返回收到的事件
+func resolveSubscription(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		root, _ := p.Info.RootValue.(map[string]interface{})
+		sub, ok := root[keyOfSubscription].(*subscription)
+		if !ok {
+			return nil, errOfSubscriptionTransport
+		}
+		if sub.started {
+			return sub.event, nil
+		}
+		if sub.source.IsValid() {
+			return nil, errOfSubscriptionFields
+		}
+		// 订阅函数使用可以取消的 context
+		p.Context = sub.ctx
+		out, err := resolve(p)
+		if err != nil {
+			return nil, err
+		}
+		sub.source = reflect.ValueOf(out)
+		return nil, nil
+	}
+}
+
+// Subscribe 执行订阅，每收到一个事件返回一个结果
+// ctx 取消或者订阅函数返回的 channel 关闭时，结果 channel 关闭
+// 查询、操作以及出错的订阅只返回一个结果
+func (cfg *SubscriptionConfig) Subscribe(ctx context.Context, params graphql.Params) <-chan *graphql.Result {
+	results := make(chan *graphql.Result)
+
+	root := params.RootObject
+	if root == nil {
+		root = make(map[string]interface{})
+	}
+	sub := &subscription{ctx: ctx}
+	root[keyOfSubscription] = sub
+	params.RootObject = root
+	params.Context = detachedContext{ctx}
+
+	send := func(result *graphql.Result) bool {
+		select {
+		case results <- result:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(results)
+		defer func() {
+			if value := recover(); value != nil {
+				// 不在 http 请求的 goroutine 中，panic 会导致程序退出
+				err := newResolveError(value, nil)
+				send(&graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+				if err.Code == CodeInternal {
+					cfg.logPanic(ctx, err)
+				}
+			}
+		}()
+
+		result := graphql.Do(params)
+		if result.HasErrors() || !sub.source.IsValid() {
+			// 出错，或者不是订阅
+			send(result)
+			return
+		}
+		if sub.source.Kind() != reflect.Chan || sub.source.IsNil() {
+			// 订阅函数没有返回 channel
+			return
+		}
+
+		sub.started = true
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: sub.source},
+		}
+		for {
+			chosen, event, ok := reflect.Select(cases)
+			if chosen == 0 || !ok {
+				// ctx 取消或者 channel 关闭
+				return
+			}
+			sub.event = event.Interface()
+			if !send(graphql.Do(params)) {
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// newSubscribeParams 根据请求参数生成执行参数，并保存原始的 variables
+func (cfg *SubscriptionConfig) newSubscribeParams(ctx context.Context, r *http.Request, opts *RequestOptions) (context.Context, graphql.Params) {
+	if opts.Variables != nil {
+		// 用于区分提交的 null 和未提交的参数
+		ctx = context.WithValue(ctx, keyOfRawVariables, opts.Variables)
+	}
+	var root map[string]interface{}
+	if cfg.RootObjectFn != nil {
+		root = cfg.RootObjectFn(ctx, r)
+	}
+	return ctx, graphql.Params{
+		Schema:         *cfg.Schema,
+		RequestString:  opts.Query,
+		VariableValues: opts.Variables,
+		OperationName:  opts.OperationName,
+		RootObject:     root,
+		Context:        ctx,
+	}
+}
+
+// formatResult 使用 FormatErrorFn 格式化结果中的错误信息
+func (cfg *SubscriptionConfig) formatResult(result *graphql.Result) *graphql.Result {
+	if cfg.FormatErrorFn != nil && len(result.Errors) > 0 {
+		formatted := make([]gqlerrors.FormattedError, len(result.Errors))
+		for i, err := range result.Errors {
+			formatted[i] = cfg.FormatErrorFn(err.OriginalError())
+		}
+		result.Errors = formatted
+	}
+	return result
+}
+
+// logPanic 记录未知 panic
+func (cfg *SubscriptionConfig) logPanic(ctx context.Context, err *ResolveError) {
+	var logger PanicLogger
+	if cfg.ResponseObjectManager != nil {
+		logger = cfg.ResponseObjectManager.PanicLogger()
+	}
+	logger.log(ctx, err)
+}
+
+// keepAlive 心跳间隔
+func (cfg *SubscriptionConfig) keepAlive() time.Duration {
+	if cfg.KeepAlive > 0 {
+		return cfg.KeepAlive
+	}
+	return DefaultKeepAlive
+}
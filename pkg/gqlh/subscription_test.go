@@ -0,0 +1,355 @@
+package gqlh_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/seerx/gql"
+	"github.com/seerx/gql/pkg/gqlh"
+)
+
+type Tick struct {
+	N int
+}
+
+type TickArg struct {
+	Count int
+}
+
+// Ticks 推送 Count 个事件后关闭 channel
+func Ticks(ctx context.Context, a *TickArg) (<-chan *Tick, error) {
+	ch := make(chan *Tick)
+	go func() {
+		defer close(ch)
+		for n := 1; n <= a.Count; n++ {
+			select {
+			case ch <- &Tick{N: n}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func Now() (*Tick, error) {
+	return &Tick{N: 0}, nil
+}
+
+func newTickGQL() *gql.GQL {
+	g := gql.NewGQL()
+	g.RegisterQuery(Now)
+	g.RegisterSubscription(Ticks)
+	return g
+}
+
+func TestSubscriptionOverHTTP(t *testing.T) {
+	h := newHandler(t, newTickGQL())
+	res := post(t, h, `subscription {Ticks(TickArg: {Count: 1}) {N}}`, nil)
+	if len(res.Errors) != 1 {
+		t.Fatalf("期望一个错误，实际 %v", res.Errors)
+	}
+	if code := res.Errors[0].Extensions["code"]; code != gqlh.CodeBadRequest {
+		t.Errorf("错误代码为 %v，期望 %s", code, gqlh.CodeBadRequest)
+	}
+}
+
+// Waits 不推送事件，客户端取消订阅时关闭 channel
+func Waits(ctx context.Context, a *TickArg) (<-chan *Tick, error) {
+	ch := make(chan *Tick)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Tokens 推送一个事件，N 为 connection_init 中 token 的长度
+func Tokens(ctx context.Context, a *TickArg) (<-chan *Tick, error) {
+	token, _ := gqlh.ConnectionParams(ctx)["token"].(string)
+	ch := make(chan *Tick, 1)
+	ch <- &Tick{N: len(token)}
+	close(ch)
+	return ch, nil
+}
+
+func newSubscriptionServer(t *testing.T) *httptest.Server {
+	g := newTickGQL()
+	g.RegisterSubscription(Waits)
+	g.RegisterSubscription(Tokens)
+	return httptest.NewServer(newHandler(t, g))
+}
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsDial 使用指定的子协议连接
+func wsDial(t *testing.T, server *httptest.Server, protocol string) *websocket.Conn {
+	t.Helper()
+	dialer := websocket.Dialer{Subprotocols: []string{protocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.Subprotocol() != protocol {
+		t.Fatalf("子协议为 %s，期望 %s", conn.Subprotocol(), protocol)
+	}
+	return conn
+}
+
+func wsSend(t *testing.T, conn *websocket.Conn, id, typ string, payload interface{}) {
+	t.Helper()
+	msg := wsMessage{ID: id, Type: typ}
+	if payload != nil {
+		msg.Payload, _ = json.Marshal(payload)
+	}
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wsRead 读取一个消息，超时或者连接关闭时测试失败
+func wsRead(t *testing.T, conn *websocket.Conn) *wsMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	msg := &wsMessage{}
+	if err := conn.ReadJSON(msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// wsExpect 读取一个消息，检查类型和 id
+func wsExpect(t *testing.T, conn *websocket.Conn, id, typ string) *wsMessage {
+	t.Helper()
+	msg := wsRead(t, conn)
+	if msg.Type != typ || msg.ID != id {
+		t.Fatalf("收到消息 %s(%s) %s，期望 %s(%s)", msg.Type, msg.ID, msg.Payload, typ, id)
+	}
+	return msg
+}
+
+// wsExpectClose 读取消息直到连接关闭，检查关闭代码
+func wsExpectClose(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if closeErr, ok := err.(*websocket.CloseError); !ok || closeErr.Code != code {
+			t.Fatalf("连接关闭的原因为 %v，期望关闭代码 %d", err, code)
+		}
+		return
+	}
+}
+
+// tickOf next/data 消息中的 N
+func tickOf(t *testing.T, msg *wsMessage, field string) interface{} {
+	t.Helper()
+	var result response
+	if err := json.Unmarshal(msg.Payload, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors[0].Message)
+	}
+	return path(result.Data, field, "N")
+}
+
+func subscribePayload(query string) map[string]interface{} {
+	return map[string]interface{}{"query": query}
+}
+
+func TestGraphQLTransportWS(t *testing.T) {
+	server := newSubscriptionServer(t)
+	defer server.Close()
+
+	conn := wsDial(t, server, gqlh.ProtocolGraphQLTransportWS)
+	defer conn.Close()
+	wsSend(t, conn, "", "connection_init", map[string]interface{}{"token": "abc"})
+	wsExpect(t, conn, "", "connection_ack")
+
+	wsSend(t, conn, "", "ping", nil)
+	wsExpect(t, conn, "", "pong")
+
+	// 每个事件一个 next，channel 关闭时 complete
+	wsSend(t, conn, "1", "subscribe", subscribePayload(`subscription {Ticks(TickArg: {Count: 2}) {N}}`))
+	for n := 1; n <= 2; n++ {
+		if v := tickOf(t, wsExpect(t, conn, "1", "next"), "Ticks"); v != float64(n) {
+			t.Errorf("第 %d 个事件为 %v", n, v)
+		}
+	}
+	wsExpect(t, conn, "1", "complete")
+
+	// 订阅函数可以获取 connection_init 的 payload
+	wsSend(t, conn, "2", "subscribe", subscribePayload(`subscription {Tokens(TickArg: {Count: 1}) {N}}`))
+	if v := tickOf(t, wsExpect(t, conn, "2", "next"), "Tokens"); v != 3.0 {
+		t.Errorf("token 的长度为 %v", v)
+	}
+	wsExpect(t, conn, "2", "complete")
+
+	// 请求错误
+	wsSend(t, conn, "3", "subscribe", subscribePayload(`subscription {Ticks {N}`))
+	wsExpect(t, conn, "3", "error")
+
+	// 查询只返回一个结果
+	wsSend(t, conn, "4", "subscribe", subscribePayload(`{Now {N}}`))
+	if v := tickOf(t, wsExpect(t, conn, "4", "next"), "Now"); v != 0.0 {
+		t.Errorf("查询的结果为 %v", v)
+	}
+	wsExpect(t, conn, "4", "complete")
+
+	// 客户端取消订阅后可以使用相同的 id
+	wsSend(t, conn, "5", "subscribe", subscribePayload(`subscription {Waits(TickArg: {Count: 1}) {N}}`))
+	wsSend(t, conn, "5", "complete", nil)
+	wsSend(t, conn, "5", "subscribe", subscribePayload(`subscription {Ticks(TickArg: {Count: 1}) {N}}`))
+	wsExpect(t, conn, "5", "next")
+	wsExpect(t, conn, "5", "complete")
+
+	// 重复的 id
+	wsSend(t, conn, "6", "subscribe", subscribePayload(`subscription {Waits(TickArg: {Count: 1}) {N}}`))
+	wsSend(t, conn, "6", "subscribe", subscribePayload(`subscription {Waits(TickArg: {Count: 1}) {N}}`))
+	wsExpectClose(t, conn, 4409)
+}
+
+func TestGraphQLTransportWSClose(t *testing.T) {
+	server := newSubscriptionServer(t)
+	defer server.Close()
+
+	// 没有 connection_init
+	conn := wsDial(t, server, gqlh.ProtocolGraphQLTransportWS)
+	wsSend(t, conn, "1", "subscribe", subscribePayload(`subscription {Ticks(TickArg: {Count: 1}) {N}}`))
+	wsExpectClose(t, conn, 4401)
+	conn.Close()
+
+	// 多次 connection_init
+	conn = wsDial(t, server, gqlh.ProtocolGraphQLTransportWS)
+	wsSend(t, conn, "", "connection_init", nil)
+	wsExpect(t, conn, "", "connection_ack")
+	wsSend(t, conn, "", "connection_init", nil)
+	wsExpectClose(t, conn, 4429)
+	conn.Close()
+
+	// 不支持的消息
+	conn = wsDial(t, server, gqlh.ProtocolGraphQLTransportWS)
+	wsSend(t, conn, "", "connection_init", nil)
+	wsExpect(t, conn, "", "connection_ack")
+	wsSend(t, conn, "", "unknown", nil)
+	wsExpectClose(t, conn, 4400)
+	conn.Close()
+}
+
+func TestGraphQLWS(t *testing.T) {
+	server := newSubscriptionServer(t)
+	defer server.Close()
+
+	conn := wsDial(t, server, gqlh.ProtocolGraphQLWS)
+	defer conn.Close()
+	wsSend(t, conn, "", "connection_init", nil)
+	wsExpect(t, conn, "", "connection_ack")
+	wsExpect(t, conn, "", "ka")
+
+	wsSend(t, conn, "1", "start", subscribePayload(`subscription {Ticks(TickArg: {Count: 2}) {N}}`))
+	for n := 1; n <= 2; n++ {
+		if v := tickOf(t, wsExpect(t, conn, "1", "data"), "Ticks"); v != float64(n) {
+			t.Errorf("第 %d 个事件为 %v", n, v)
+		}
+	}
+	wsExpect(t, conn, "1", "complete")
+
+	// 重复的 id 返回错误，不关闭连接
+	wsSend(t, conn, "2", "start", subscribePayload(`subscription {Waits(TickArg: {Count: 1}) {N}}`))
+	wsSend(t, conn, "2", "start", subscribePayload(`subscription {Waits(TickArg: {Count: 1}) {N}}`))
+	wsExpect(t, conn, "2", "error")
+	wsSend(t, conn, "2", "stop", nil)
+
+	wsSend(t, conn, "3", "start", subscribePayload(`subscription {Ticks(TickArg: {Count: 1}) {N}}`))
+	wsExpect(t, conn, "3", "data")
+	wsExpect(t, conn, "3", "complete")
+}
+
+// sseEvent SSE 事件
+type sseEvent struct {
+	event string
+	data  string
+}
+
+// sseSubscribe 通过 SSE 订阅，读取所有事件直到连接关闭
+func sseSubscribe(t *testing.T, server *httptest.Server, query string) []sseEvent {
+	t.Helper()
+	body, _ := json.Marshal(subscribePayload(query))
+	req, _ := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type 为 %s", ct)
+	}
+
+	var events []sseEvent
+	var event sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		case line == "" && event.event != "":
+			events = append(events, event)
+			event = sseEvent{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestSSE(t *testing.T) {
+	server := newSubscriptionServer(t)
+	defer server.Close()
+
+	events := sseSubscribe(t, server, `subscription {Ticks(TickArg: {Count: 2}) {N}}`)
+	if len(events) != 3 || events[2].event != "complete" {
+		t.Fatalf("收到的事件为 %v", events)
+	}
+	for n, event := range events[:2] {
+		var result response
+		json.Unmarshal([]byte(event.data), &result)
+		if event.event != "next" || path(result.Data, "Ticks", "N") != float64(n+1) {
+			t.Errorf("第 %d 个事件为 %v", n+1, event)
+		}
+	}
+
+	// 订阅请求只能有一个字段
+	events = sseSubscribe(t, server, `subscription {a: Ticks(TickArg: {Count: 1}) {N} b: Ticks(TickArg: {Count: 1}) {N}}`)
+	if len(events) != 2 || events[0].event != "next" || events[1].event != "complete" {
+		t.Fatalf("收到的事件为 %v", events)
+	}
+	var result response
+	json.Unmarshal([]byte(events[0].data), &result)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != gqlh.CodeGraphQLValidationFailed {
+		t.Errorf("多个字段的订阅返回 %s", events[0].data)
+	}
+}
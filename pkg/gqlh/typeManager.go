@@ -0,0 +1,175 @@
+package gqlh
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"github.com/seerx/gql/pkg/utils"
+)
+
+// TypeManager 自定义类型管理，如枚举、标量、接口等
+type TypeManager struct {
+	enumMap     map[reflect.Type]*graphql.Enum
+	scalarMap   map[reflect.Type]*graphql.Scalar
+	abstractMap map[reflect.Type]*AbstractType
+	keyValueMap map[string]graphql.Output // map 对应的 {key, value} 对象
+	// 类型命名
+	naming      TypeNaming
+	fieldNaming FieldNaming             // 字段命名策略
+	typeNames   map[reflect.Type]string // go 类型对应的 graphql 类型名称
+	usedNames   map[string]reflect.Type // 已经使用的名称
+	conflicts   []*TypeConflict         // 名称冲突的类型
+}
+
+// AbstractType 接口类型定义，对应 graphql 的 interface 或 union
+type AbstractType struct {
+	Type    reflect.Type   // go 接口类型
+	Impls   []reflect.Type // 实现接口的结构类型
+	IsUnion bool           // 是否生成 union
+}
+
+var errOfAbstract = errors.New("Param iface must be a nil pointer of interface like -- (*Searchable)(nil), and impls must be structs (or pointers) which implement the interface")
+var errOfEnum = errors.New("Param values of RegisterEnum must be a map like -- map[string]interface{}{\"NAME\": Constant}, and all constants must be the same named string or integer type")
+
+// NewTypeManager 创建自定义类型管理器
+func NewTypeManager() *TypeManager {
+	return &TypeManager{
+		enumMap:     make(map[reflect.Type]*graphql.Enum),
+		scalarMap:   make(map[reflect.Type]*graphql.Scalar),
+		abstractMap: make(map[reflect.Type]*AbstractType),
+		keyValueMap: make(map[string]graphql.Output),
+		typeNames:   make(map[reflect.Type]string),
+		usedNames:   make(map[string]reflect.Type),
+	}
+}
+
+// RegisterEnum 注册枚举类型
+// @param values 枚举值名称与 go 常量的对应关系，所有常量必须是同一个自定义的 string 或整数类型
+// 例如 map[string]interface{}{"PAID": OrderPaid, "SHIPPED": OrderShipped}
+func (tm *TypeManager) RegisterEnum(values map[string]interface{}) {
+	if len(values) == 0 {
+		panic(errOfEnum)
+	}
+
+	var typ reflect.Type
+	enumValues := graphql.EnumValueConfigMap{}
+	for name, val := range values {
+		if val == nil {
+			panic(errOfEnum)
+		}
+		vtyp := reflect.TypeOf(val)
+		if typ == nil {
+			typ = vtyp
+		} else if typ != vtyp {
+			// 常量类型不一致
+			panic(errOfEnum)
+		}
+		enumValues[name] = &graphql.EnumValueConfig{
+			Value: val,
+		}
+	}
+
+	kind := typ.Kind()
+	if typ.PkgPath() == "" || !(utils.IsStringType(kind) || utils.IsIntType(kind)) {
+		// 必须是自定义的 string 或整数类型
+		panic(errOfEnum)
+	}
+
+	if tm.IsEnum(typ) || tm.IsScalar(typ) {
+		panic(fmt.Errorf("Enum [%s] is Registered", typ.Name()))
+	}
+
+	tm.enumMap[typ] = graphql.NewEnum(graphql.EnumConfig{
+		Name:        tm.TypeName(typ),
+		Description: typeDoc(typ),
+		Values:      enumValues,
+	})
+}
+
+// IsEnum 判断是否是已经注册的枚举类型
+func (tm *TypeManager) IsEnum(typ reflect.Type) bool {
+	_, ok := tm.enumMap[typ]
+	return ok
+}
+
+// FindType 查找自定义类型对应的 graphql 类型，找不到时返回 nil
+func (tm *TypeManager) FindType(typ reflect.Type) graphql.Output {
+	if enum, ok := tm.enumMap[typ]; ok {
+		return enum
+	}
+	if scalar, ok := tm.scalarMap[typ]; ok {
+		return scalar
+	}
+	if typ.Kind() == reflect.Map {
+		// map 默认以 JSON 对象的形式输入输出
+		return JSONScalar
+	}
+	return nil
+}
+
+// RegisterAbstract 注册接口类型及其实现结构
+// @param iface 接口的空指针，例如 (*Searchable)(nil)
+// @param impls 实现接口的结构（或结构指针）
+// @param isUnion 为 true 时生成 graphql union，否则生成 graphql interface
+func (tm *TypeManager) RegisterAbstract(iface interface{}, impls []interface{}, isUnion bool) {
+	typ := reflect.TypeOf(iface)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Interface {
+		panic(errOfAbstract)
+	}
+	typ = typ.Elem()
+	if len(impls) == 0 {
+		panic(errOfAbstract)
+	}
+
+	if _, ok := tm.abstractMap[typ]; ok {
+		panic(fmt.Errorf("Interface [%s] is Registered", typ.Name()))
+	}
+
+	abs := &AbstractType{
+		Type:    typ,
+		IsUnion: isUnion,
+	}
+	for _, impl := range impls {
+		implType := reflect.TypeOf(impl)
+		if implType == nil {
+			panic(errOfAbstract)
+		}
+		if implType.Kind() == reflect.Ptr {
+			implType = implType.Elem()
+		}
+		if implType.Kind() != reflect.Struct || !reflect.PtrTo(implType).Implements(typ) {
+			panic(errOfAbstract)
+		}
+		abs.Impls = append(abs.Impls, implType)
+	}
+	tm.abstractMap[typ] = abs
+}
+
+// FindAbstract 查找已经注册的接口类型，找不到时返回 nil
+func (tm *TypeManager) FindAbstract(typ reflect.Type) *AbstractType {
+	return tm.abstractMap[typ]
+}
+
+// InterfacesOf 获取结构实现的 graphql interface（不包括 union）
+func (tm *TypeManager) InterfacesOf(typ reflect.Type) []*AbstractType {
+	var list []*AbstractType
+	for _, abs := range tm.abstractMap {
+		if abs.IsUnion {
+			continue
+		}
+		for _, impl := range abs.Impls {
+			if impl == typ {
+				list = append(list, abs)
+				break
+			}
+		}
+	}
+	// 保证生成的 schema 顺序稳定
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Type.Name() < list[j].Type.Name()
+	})
+	return list
+}
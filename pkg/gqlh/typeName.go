@@ -0,0 +1,146 @@
+package gqlh
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/seerx/gql/pkg/utils"
+)
+
+// TypeNaming 结构、枚举等生成 graphql 类型时的命名策略
+type TypeNaming int
+
+const (
+	// TypeNamingDefault 使用 go 类型名称，如 Goods
+	TypeNamingDefault TypeNaming = iota
+	// TypeNamingPackagePrefix 使用包名加类型名称，如 shop.Goods 命名为 ShopGoods
+	TypeNamingPackagePrefix
+)
+
+// GraphQLNamer 实现此接口的类型使用 GraphQLName 的返回值作为 graphql 类型名称
+// 也可以在结构中加入 _ struct{} `gql:"typename=ShopGoods"` 标记指定名称
+type GraphQLNamer interface {
+	GraphQLName() string
+}
+
+var typeOfGraphQLNamer = reflect.TypeOf((*GraphQLNamer)(nil)).Elem()
+
+// TypeConflict 不同的 go 类型生成了相同的 graphql 类型名称，后注册的类型被重新命名
+type TypeConflict struct {
+	Name    string       // 冲突的名称
+	Exists  reflect.Type // 先使用该名称的类型
+	Type    reflect.Type // 后使用该名称的类型
+	Renamed string       // Type 重新命名后的名称
+}
+
+// String 转为字符串
+func (c *TypeConflict) String() string {
+	return fmt.Sprintf("%s: %s, %s\t[renamed to %s]", c.Name, fullTypeName(c.Exists), fullTypeName(c.Type), c.Renamed)
+}
+
+// SetNaming 设置命名策略，必须在生成 schema 之前设置
+func (tm *TypeManager) SetNaming(naming TypeNaming) {
+	tm.naming = naming
+}
+
+// Conflicts 名称冲突的类型列表
+func (tm *TypeManager) Conflicts() []*TypeConflict {
+	return tm.conflicts
+}
+
+// TypeName 获取 go 类型对应的 graphql 类型名称
+// 优先使用 GraphQLName 方法或者 typename 标记指定的名称，然后按照命名策略生成
+// 名称已经被其他类型使用时，使用包名加类型名称重新命名，并记录冲突
+func (tm *TypeManager) TypeName(typ reflect.Type) string {
+	if name, ok := tm.typeNames[typ]; ok {
+		return name
+	}
+
+	name := explicitTypeName(typ)
+	if name == "" {
+		if tm.naming == TypeNamingPackagePrefix {
+			name = packagePrefix(typ) + typ.Name()
+		} else {
+			name = typ.Name()
+		}
+	}
+
+	if exists, ok := tm.usedNames[name]; ok && exists != typ {
+		conflict := &TypeConflict{
+			Name:   name,
+			Exists: exists,
+			Type:   typ,
+		}
+		renamed := packagePrefix(typ) + typ.Name()
+		for n := 2; ; n++ {
+			if _, ok := tm.usedNames[renamed]; !ok {
+				break
+			}
+			renamed = fmt.Sprintf("%s%s%d", packagePrefix(typ), typ.Name(), n)
+		}
+		conflict.Renamed = renamed
+		tm.conflicts = append(tm.conflicts, conflict)
+		name = renamed
+	}
+
+	tm.typeNames[typ] = name
+	tm.usedNames[name] = typ
+	return name
+}
+
+// reserveName 记录自定义标量等已经指定的名称，避免其他类型使用
+func (tm *TypeManager) reserveName(name string, typ reflect.Type) {
+	tm.typeNames[typ] = name
+	tm.usedNames[name] = typ
+}
+
+// explicitTypeName 获取 GraphQLName 方法或者 typename 标记指定的名称
+func explicitTypeName(typ reflect.Type) string {
+	if typ.Implements(typeOfGraphQLNamer) || reflect.PtrTo(typ).Implements(typeOfGraphQLNamer) {
+		if typ.Kind() != reflect.Interface {
+			if namer, ok := reflect.New(typ).Interface().(GraphQLNamer); ok {
+				return namer.GraphQLName()
+			}
+		}
+	}
+	if typ.Kind() == reflect.Struct {
+		for n := 0; n < typ.NumField(); n++ {
+			field := typ.Field(n)
+			if field.Name != "_" {
+				continue
+			}
+			if name := utils.ParseGqlTagValue(&field, "typename"); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// packagePrefix 包名作为名称前缀，首字母大写，如 github.com/x/shop 为 Shop
+func packagePrefix(typ reflect.Type) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return -1
+	}, path.Base(typ.PkgPath()))
+	if name == "" {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// fullTypeName go 类型的完整名称，如 github.com/x/shop.Goods
+func fullTypeName(typ reflect.Type) string {
+	if typ == nil {
+		return ""
+	}
+	if typ.PkgPath() == "" {
+		return typ.String()
+	}
+	return typ.PkgPath() + "." + typ.Name()
+}
@@ -0,0 +1,84 @@
+package gqlh_test
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/seerx/gql"
+	"github.com/seerx/gql/pkg/gqlh"
+	"github.com/seerx/gql/pkg/gqlh/internal/shop"
+	"github.com/seerx/gql/pkg/gqlh/internal/store"
+)
+
+func ShopGoods() (*shop.Goods, error) {
+	return &shop.Goods{ID: "1", Price: 9.5}, nil
+}
+
+func StoreGoods() (*store.Goods, error) {
+	return &store.Goods{ID: "1", Stock: 3}, nil
+}
+
+// goodsField 区分 shop.Goods 和 store.Goods 的字段，shop.Goods 为 Price，store.Goods 为 Stock
+func goodsField(t *testing.T, s *graphql.Schema, name string) string {
+	obj, ok := s.Type(name).(*graphql.Object)
+	if !ok {
+		t.Fatalf("没有类型 %s", name)
+	}
+	for _, f := range []string{"Price", "Stock"} {
+		if _, ok := obj.Fields()[f]; ok {
+			return f
+		}
+	}
+	return ""
+}
+
+func TestTypeNameConflict(t *testing.T) {
+	cases := []struct {
+		name    string
+		fns     []interface{}
+		goods   string // 保留 Goods 名称的结构的字段
+		renamed string // 重新命名后的名称
+	}{
+		{"shop first", []interface{}{ShopGoods, StoreGoods}, "Price", "StoreGoods"},
+		{"store first", []interface{}{StoreGoods, ShopGoods}, "Stock", "ShopGoods"},
+	}
+	for _, c := range cases {
+		// map 的遍历顺序是随机的，多次生成 schema，名称必须一致
+		for n := 0; n < 20; n++ {
+			g := gql.NewGQL()
+			for _, fn := range c.fns {
+				g.RegisterQuery(fn)
+			}
+			s, err := g.GetSchema()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if field := goodsField(t, s, "Goods"); field != c.goods {
+				t.Fatalf("%s: 第 %d 次生成的 Goods 的字段为 %s，期望 %s", c.name, n, field, c.goods)
+			}
+			if field := goodsField(t, s, c.renamed); field == c.goods {
+				t.Fatalf("%s: 第 %d 次生成的 %s 的字段为 %s", c.name, n, c.renamed, field)
+			}
+		}
+	}
+}
+
+func TestTypeNamePackagePrefix(t *testing.T) {
+	g := gql.NewGQL()
+	g.SetTypeNaming(gqlh.TypeNamingPackagePrefix)
+	g.RegisterQuery(ShopGoods)
+	g.RegisterQuery(StoreGoods)
+	s, err := g.GetSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Type("Goods") != nil {
+		t.Error("使用包名前缀时不应该有 Goods 类型")
+	}
+	if field := goodsField(t, s, "ShopGoods"); field != "Price" {
+		t.Errorf("ShopGoods 的字段为 %s", field)
+	}
+	if field := goodsField(t, s, "StoreGoods"); field != "Stock" {
+		t.Errorf("StoreGoods 的字段为 %s", field)
+	}
+}
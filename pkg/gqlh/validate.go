@@ -1,9 +1,9 @@
 package gqlh
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/graphql-go/graphql"
@@ -19,6 +19,8 @@ type InputValidator struct {
 	requestObjectManager *RequestObjectManager
 	graphqlParam         graphql.ResolveParams
 	params               map[string]*paramStatus
+	states               map[string]InputState // 参数的提交状态
+	flattenArgs          bool                  // 输入结构的字段直接作为参数
 }
 
 type paramStatus struct {
@@ -34,19 +36,40 @@ func (v *InputValidator) Requires(params ...string) {
 		for n := range sub {
 			k := strings.Join(sub[:n+1], ".")
 			if msg, ok := v.params[k]; ok {
-				panic(errors.New(msg.Error))
+				panic(&ValidationError{Param: k, Message: msg.Error})
 			}
 		}
 	}
 }
 
+// State 获取参数的提交状态：未提交、显式提交 null 或者提交了值
+// @param param 参数名称，嵌套结构使用 . 分隔，如 goods.name、items.2.name
+func (v *InputValidator) State(param string) InputState {
+	return v.states[param]
+}
+
+// IsAbsent 参数是否未提交
+func (v *InputValidator) IsAbsent(param string) bool {
+	return v.State(param) == InputAbsent
+}
+
+// IsNull 参数是否显式提交了 null
+func (v *InputValidator) IsNull(param string) bool {
+	return v.State(param) == InputNull
+}
+
+// IsSet 参数是否提交了值
+func (v *InputValidator) IsSet(param string) bool {
+	return v.State(param) == InputSet
+}
+
 func (v *InputValidator) checkValidate(paramName string, field *Field, val interface{}) {
 	//tp := reflect.TypeOf(val)
 	//kd := tp.Kind()
 	for _, ck := range field.Prop.ValChecker {
 		err := ck.Passed(val)
 		if err != nil {
-			panic(paramName + ":" + err.Error())
+			panic(&ValidationError{Param: paramName, Message: paramName + ":" + err.Error()})
 			//v.params[paramName] = &paramStatus{
 			//	Error: err.Error(),
 			//}
@@ -71,26 +94,7 @@ func (v *InputValidator) checkValidate(paramName string, field *Field, val inter
 	}
 }
 
-//var a uin
-
-var aryTypes = map[string]reflect.Type{
-	"string":  reflect.TypeOf([]string{}),
-	"int":     reflect.TypeOf([]int{}),
-	"int8":    reflect.TypeOf([]int{}),
-	"int16":   reflect.TypeOf([]int{}),
-	"int32":   reflect.TypeOf([]int{}),
-	"int64":   reflect.TypeOf([]int{}),
-	"uint":    reflect.TypeOf([]uint{}),
-	"uint8":   reflect.TypeOf([]uint{}),
-	"uint16":  reflect.TypeOf([]uint{}),
-	"uint32":  reflect.TypeOf([]uint{}),
-	"uint64":  reflect.TypeOf([]uint{}),
-	"float":   reflect.TypeOf([]float64{}),
-	"float32": reflect.TypeOf([]float64{}),
-	"float64": reflect.TypeOf([]float64{}),
-}
-
-func (v *InputValidator) parseField(parentParam string, input map[string]interface{}, arg *RequestObject) reflect.Value {
+func (v *InputValidator) parseField(parentParam string, input map[string]interface{}, raw map[string]interface{}, arg *RequestObject) reflect.Value {
 	res := reflect.New(arg.Param.Prop.RealType)
 	elem := res.Elem()
 
@@ -100,21 +104,38 @@ func (v *InputValidator) parseField(parentParam string, input map[string]interfa
 			paramKey = parentParam + "." + paramKey
 		}
 
+		resField := utils.FieldByIndex(elem, field.Index)
+
+		state := inputState(input, raw, field.JSONName)
+		v.states[paramKey] = state
 		inputVal, ok := input[field.JSONName]
+		if !ok && field.DefaultValue != nil {
+			// 未提交时使用默认值
+			inputVal, ok = field.DefaultValue, true
+		}
 		if !ok {
-			// 未提交的参数
-			v.params[paramKey] = &paramStatus{
-				Error: fmt.Sprintf("参数 %s 未提交", paramKey),
+			if !utils.IsNullableKind(resField.Kind()) {
+				// 未提交的参数
+				v.params[paramKey] = &paramStatus{
+					Error: fmt.Sprintf("参数 %s 未提交", paramKey),
+				}
 			}
+			// 指针等可以为空的字段，未提交或者提交 null 时保持 nil
 			continue
 		}
-
-		resField := elem.FieldByName(field.Name)
-		if utils.IsStructType(field.Prop.Kind) && !utils.IsTimeType(field.Prop.RealType) {
+		subRaw, _ := raw[field.JSONName].(map[string]interface{})
+		// 自定义标量，ParseValue 已经转换为 go 类型，可能是切片，如 []byte
+		isScalar := v.requestObjectManager.typeManager.IsScalar(resField.Type()) ||
+			v.requestObjectManager.typeManager.IsScalar(field.Prop.RealType)
+		if !field.Prop.IsList && !isScalar &&
+			utils.IsStructType(field.Prop.Kind) && !utils.IsTimeType(field.Prop.RealType) {
 			// 结构类型，且不是时间类型
 			inputMap := inputVal.(map[string]interface{})
 			argType := v.requestObjectManager.FindOrRegisterObject(field, "")
-			val := v.parseField(paramKey, inputMap, argType)
+			val := v.parseField(paramKey, inputMap, subRaw, argType)
+			if resField.Kind() != reflect.Ptr {
+				val = val.Elem()
+			}
 			resField.Set(val)
 			v.checkValidate(paramKey, field, inputVal)
 			continue
@@ -124,49 +145,21 @@ func (v *InputValidator) parseField(parentParam string, input map[string]interfa
 
 		vType := reflect.TypeOf(inputVal)
 
-		if field.Prop.IsList && vType.Kind() == reflect.Slice {
-			ary := inputVal.([]interface{})
-			if utils.IsStructType(field.Prop.Kind) && !utils.IsTimeType(field.Prop.RealType) {
-				// 结构类型
-				v.params[paramKey] = &paramStatus{
-					Error: fmt.Sprintf("%s 暂不支持结构数组提交的参数",
-						paramKey),
-				}
-				continue
-				//item := reflect.New(field.Prop.RealType)
-			} else {
-				aryType, ok := aryTypes[field.Prop.RealType.Name()]
-				if !ok {
-					v.params[paramKey] = &paramStatus{
-						Error: fmt.Sprintf("参数 %s 找不到对应的数组类型 %s",
-							paramKey,
-							field.Prop.RealType.Name()),
-					}
-					continue
-				}
-				//var intfType = reflect.TypeOf((interface{})(nil))
-				//var typ = vType.Elem()
-				val = reflect.MakeSlice(aryType, 0, len(ary))
-				//var aryVal []interface{}
-				for _, a := range ary {
-					val = reflect.Append(val, reflect.ValueOf(a))
-					//aryVal = append(aryVal, a)
-				}
-
-				//val = reflect.ValueOf(aryVal)
-			}
+		if field.Prop.IsList && vType.Kind() == reflect.Slice && !isScalar &&
+			utils.IsStructType(field.Prop.Kind) && !utils.IsTimeType(field.Prop.RealType) {
+			// 结构数组，包括定长数组和多维数组
+			argType := v.requestObjectManager.FindObject(field.Prop.RealType)
+			val = v.parseStructItem(paramKey, inputVal, raw[field.JSONName], resField.Type(), argType)
 		} else {
-			val = reflect.ValueOf(inputVal)
-
-			if val.Type() != resField.Type() {
-				// 类型不匹配
-				v.params[paramKey] = &paramStatus{
-					Error: fmt.Sprintf("参数 %s 类型不匹配，期望类型：%s, 实际类型：%s",
-						paramKey,
-						resField.Type().Name(),
-						val.Type().Name()),
-				}
-				continue
+			// 转换为 go 定义的类型，包括各种宽度的数值、枚举、自定义标量和列表
+			var err error
+			val, err = utils.ConvertValue(inputVal, resField.Type())
+			if err != nil {
+				// 类型不匹配或者数值溢出，不能使用零值继续执行
+				panic(&ValidationError{
+					Param:   paramKey,
+					Message: fmt.Sprintf("参数 %s %s", paramKey, err.Error()),
+				})
 			}
 		}
 
@@ -179,15 +172,105 @@ func (v *InputValidator) parseField(parentParam string, input map[string]interfa
 	return res
 }
 
-// ParseInput 解析输入参数
-func (v *InputValidator) ParseInput(param *graphql.ResolveParams, arg *RequestObject) (reflect.Value, error) {
-	pmap, ok := param.Args[arg.Name].(map[string]interface{})
+// parseStructList 解析结构数组，每个元素的参数名称为 上级参数.序号，如 items.2.name
+// listType 可以是切片或者定长数组，元素可以是结构、结构指针或者下一级数组
+func (v *InputValidator) parseStructList(parentParam string, input []interface{}, raw []interface{}, listType reflect.Type, arg *RequestObject) reflect.Value {
+	var list reflect.Value
+	if listType.Kind() == reflect.Array {
+		if len(input) != listType.Len() {
+			panic(&ValidationError{
+				Param:   parentParam,
+				Message: fmt.Sprintf("参数 %s 长度必须是 %d，实际长度：%d", parentParam, listType.Len(), len(input)),
+			})
+		}
+		list = reflect.New(listType).Elem()
+	} else {
+		list = reflect.MakeSlice(listType, len(input), len(input))
+	}
+	for n, item := range input {
+		paramKey := strconv.Itoa(n)
+		if parentParam != "" {
+			paramKey = parentParam + "." + paramKey
+		}
+		var rawItem interface{}
+		if n < len(raw) {
+			rawItem = raw[n]
+		}
+		list.Index(n).Set(v.parseStructItem(paramKey, item, rawItem, listType.Elem(), arg))
+	}
+	return list
+}
+
+// parseStructItem 解析结构或结构数组，typ 为对应的 go 类型，可以是指针
+func (v *InputValidator) parseStructItem(paramKey string, input interface{}, raw interface{}, typ reflect.Type, arg *RequestObject) reflect.Value {
+	if input == nil {
+		if !utils.IsNullableKind(typ.Kind()) {
+			v.params[paramKey] = &paramStatus{
+				Error: fmt.Sprintf("参数 %s 未提交", paramKey),
+			}
+		}
+		return reflect.Zero(typ)
+	}
+	base := typ
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+
+	var val reflect.Value
+	switch base.Kind() {
+	case reflect.Slice, reflect.Array:
+		ary, _ := input.([]interface{})
+		rawList, _ := raw.([]interface{})
+		val = v.parseStructList(paramKey, ary, rawList, base, arg)
+	default:
+		inputMap, _ := input.(map[string]interface{})
+		rawMap, _ := raw.(map[string]interface{})
+		val = v.parseField(paramKey, inputMap, rawMap, arg).Elem()
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		ptr := reflect.New(base)
+		ptr.Elem().Set(val)
+		return ptr
+	}
+	return val
+}
+
+// ParseInputList 解析结构数组输入参数，用于批量提交
+// @param listType 函数参数的类型，如 []*Goods
+func (v *InputValidator) ParseInputList(param *graphql.ResolveParams, arg *RequestObject, listType reflect.Type) (reflect.Value, error) {
+	ary, ok := param.Args[arg.Name].([]interface{})
 	if !ok {
 		return reflect.ValueOf(nil), fmt.Errorf("Required arguments: %s. ", arg.Name)
 	}
 	v.params = make(map[string]*paramStatus)
+	v.states = make(map[string]InputState)
+
+	rawList, _ := rawArguments(param)[arg.Name].([]interface{})
+	return v.parseStructList("", ary, rawList, listType, arg), nil
+}
 
-	return v.parseField("", pmap, arg), nil
+// ParseInput 解析输入参数
+func (v *InputValidator) ParseInput(param *graphql.ResolveParams, arg *RequestObject) (reflect.Value, error) {
+	var pmap map[string]interface{}
+	if v.flattenArgs {
+		// 结构字段直接作为参数
+		pmap = param.Args
+	} else {
+		var ok bool
+		pmap, ok = param.Args[arg.Name].(map[string]interface{})
+		if !ok {
+			return reflect.ValueOf(nil), fmt.Errorf("Required arguments: %s. ", arg.Name)
+		}
+	}
+	v.params = make(map[string]*paramStatus)
+	v.states = make(map[string]InputState)
+
+	raw := rawArguments(param)
+	if !v.flattenArgs {
+		raw, _ = raw[arg.Name].(map[string]interface{})
+	}
+	return v.parseField("", pmap, raw, arg), nil
 }
 
 // ParseInput1 解析输入参数
@@ -0,0 +1,158 @@
+package gqlh_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/seerx/gql"
+	"github.com/seerx/gql/pkg/gqlh"
+)
+
+type GoodsInput struct {
+	Limit  uint8
+	Offset *int16
+	Ratio  float32
+	Sizes  [2]int
+	Grid   [][]string
+	Tags   []string
+	Attrs  map[string]int
+	Labels map[string]string `gql:"map=kv"`
+	Note   *string
+}
+
+type FlatGoodsInput struct {
+	_     struct{} `gql:"flatten"`
+	ID    string
+	Limit uint8 `gql:"le=100"`
+}
+
+// Echo 以 JSON 的形式返回解析后的输入参数
+type Echo struct {
+	JSON string
+	Note string // Note 参数的提交状态
+}
+
+func EchoGoods(in *GoodsInput, v *gqlh.InputValidator) (*Echo, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	note := "set"
+	if v.IsAbsent("Note") {
+		note = "absent"
+	} else if v.IsNull("Note") {
+		note = "null"
+	}
+	return &Echo{JSON: string(data), Note: note}, nil
+}
+
+func FlatGoods(in *FlatGoodsInput) (*Echo, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	return &Echo{JSON: string(data)}, nil
+}
+
+func newGoodsGQL() *gql.GQL {
+	g := gql.NewGQL()
+	g.RegisterQuery(EchoGoods)
+	g.RegisterQuery(FlatGoods)
+	return g
+}
+
+// goodsArgs EchoGoods 的参数，不包括 Limit、Sizes、Offset 和 Note
+const goodsArgs = `Ratio: 1.5, Grid: [["a"], ["b", "c"]], Tags: ["x"], Attrs: {a: 1, b: 2}, Labels: [{key: "k", value: "v"}]`
+
+func TestInputCoercion(t *testing.T) {
+	handler := newHandler(t, newGoodsGQL())
+
+	res := post(t, handler, `{EchoGoods(GoodsInput: {`+goodsArgs+`, Limit: 200, Sizes: [3, 4], Offset: -3}) {JSON Note}}`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors[0].Message)
+	}
+	var got GoodsInput
+	if err := json.Unmarshal([]byte(path(res.Data, "EchoGoods", "JSON").(string)), &got); err != nil {
+		t.Fatal(err)
+	}
+	offset := int16(-3)
+	want := GoodsInput{
+		Limit:  200,
+		Offset: &offset,
+		Ratio:  1.5,
+		Sizes:  [2]int{3, 4},
+		Grid:   [][]string{{"a"}, {"b", "c"}},
+		Tags:   []string{"x"},
+		Attrs:  map[string]int{"a": 1, "b": 2},
+		Labels: map[string]string{"k": "v"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("解析的参数为 %+v，期望 %+v", got, want)
+	}
+
+	res = post(t, handler, `{FlatGoods(ID: "1", Limit: 3) {JSON}}`, nil)
+	if v := path(res.Data, "FlatGoods", "JSON"); v != `{"ID":"1","Limit":3}` {
+		t.Errorf("平铺参数解析的结果为 %v %v", v, res.Errors)
+	}
+}
+
+func TestInputNullAndAbsent(t *testing.T) {
+	handler := newHandler(t, newGoodsGQL())
+	args := goodsArgs + `, Limit: 1, Sizes: [1, 2]`
+	query := `query ($note: String) {EchoGoods(GoodsInput: {` + args + `, Note: $note}) {Note}}`
+	cases := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		// graphql-go 不支持 null 字面量，只能通过 variables 提交 null
+		{"literal absent", `{EchoGoods(GoodsInput: {` + args + `}) {Note}}`, nil, "absent"},
+		{"literal set", `{EchoGoods(GoodsInput: {` + args + `, Note: "n"}) {Note}}`, nil, "set"},
+		{"variable absent", query, map[string]interface{}{}, "absent"},
+		{"variable null", query, map[string]interface{}{"note": nil}, "null"},
+		{"variable set", query, map[string]interface{}{"note": "n"}, "set"},
+	}
+	for _, c := range cases {
+		res := post(t, handler, c.query, c.variables)
+		if len(res.Errors) > 0 {
+			t.Errorf("%s: %s", c.name, res.Errors[0].Message)
+			continue
+		}
+		if v := path(res.Data, "EchoGoods", "Note"); v != c.want {
+			t.Errorf("%s: 状态为 %v，期望 %s", c.name, v, c.want)
+		}
+	}
+}
+
+func TestInputValidationFailed(t *testing.T) {
+	handler := newHandler(t, newGoodsGQL())
+	cases := []struct {
+		name  string
+		query string
+		param string
+	}{
+		{"uint8 overflow", `{EchoGoods(GoodsInput: {` + goodsArgs + `, Limit: 300, Sizes: [1, 2]}) {JSON}}`, "Limit"},
+		{"negative uint8", `{EchoGoods(GoodsInput: {` + goodsArgs + `, Limit: -1, Sizes: [1, 2]}) {JSON}}`, "Limit"},
+		{"int16 overflow", `{EchoGoods(GoodsInput: {` + goodsArgs + `, Limit: 1, Sizes: [1, 2], Offset: 40000}) {JSON}}`, "Offset"},
+		{"array length", `{EchoGoods(GoodsInput: {` + goodsArgs + `, Limit: 1, Sizes: [1]}) {JSON}}`, "Sizes"},
+		{"rule", `{FlatGoods(ID: "1", Limit: 101) {JSON}}`, "Limit"},
+	}
+	for _, c := range cases {
+		res := post(t, handler, c.query, nil)
+		if len(res.Errors) != 1 {
+			t.Errorf("%s: 期望一个错误，实际 %v", c.name, res.Errors)
+			continue
+		}
+		ext := res.Errors[0].Extensions
+		if ext["code"] != gqlh.CodeValidationFailed || ext["param"] != c.param {
+			t.Errorf("%s: extensions 为 %v", c.name, ext)
+		}
+		for _, field := range []string{"EchoGoods", "FlatGoods"} {
+			if v := path(res.Data, field); v != nil {
+				t.Errorf("%s: 参数不正确时执行了函数，结果为 %v", c.name, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package gqlh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// 支持的 WebSocket 子协议
+const (
+	// ProtocolGraphQLWS subscriptions-transport-ws 使用的 graphql-ws 协议
+	ProtocolGraphQLWS = "graphql-ws"
+	// ProtocolGraphQLTransportWS graphql-ws 库使用的 graphql-transport-ws 协议
+	ProtocolGraphQLTransportWS = "graphql-transport-ws"
+)
+
+// 消息类型
+const (
+	wsConnectionInit      = "connection_init"
+	wsConnectionAck       = "connection_ack"
+	wsConnectionError     = "connection_error"
+	wsConnectionTerminate = "connection_terminate"
+	wsKeepAlive           = "ka"
+	wsStart               = "start"
+	wsStop                = "stop"
+	wsData                = "data"
+	wsSubscribe           = "subscribe"
+	wsNext                = "next"
+	wsPing                = "ping"
+	wsPong                = "pong"
+	wsError               = "error"
+	wsComplete            = "complete"
+)
+
+// graphql-transport-ws 协议的关闭代码
+const (
+	wsCloseBadRequest     = 4400
+	wsCloseUnauthorized   = 4401
+	wsCloseInitTimeout    = 4408
+	wsCloseDuplicateID    = 4409
+	wsCloseTooManyInits   = 4429
+	wsConnectionInitLimit = 10 * time.Second
+)
+
+const keyOfConnectionParams contextKey = "gql.connectionParams"
+
+// ConnectionParams 获取 WebSocket 连接时 connection_init 消息中的 payload，如认证信息
+// 可以在注入函数中通过 ctx 获取
+func ConnectionParams(ctx context.Context) map[string]interface{} {
+	params, _ := ctx.Value(keyOfConnectionParams).(map[string]interface{})
+	return params
+}
+
+// IsWebSocketUpgrade 是否是 WebSocket 连接请求
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return websocket.IsWebSocketUpgrade(r)
+}
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// WebSocketHandler 通过 WebSocket 执行订阅，支持 graphql-ws 和 graphql-transport-ws 协议
+type WebSocketHandler struct {
+	cfg      *SubscriptionConfig
+	upgrader websocket.Upgrader
+}
+
+// NewWebSocketHandler 创建 WebSocket 订阅处理器
+// @param checkOrigin 检查请求来源，为 nil 时只允许同源请求
+func NewWebSocketHandler(cfg *SubscriptionConfig, checkOrigin func(r *http.Request) bool) *WebSocketHandler {
+	return &WebSocketHandler{
+		cfg: cfg,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{ProtocolGraphQLTransportWS, ProtocolGraphQLWS},
+			CheckOrigin:  checkOrigin,
+		},
+	}
+}
+
+// ServeHTTP 处理 WebSocket 连接
+func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 已经返回了错误信息
+		return
+	}
+	protocol := conn.Subprotocol()
+	if protocol == "" {
+		// 没有指定子协议时使用 graphql-ws
+		protocol = ProtocolGraphQLWS
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	c := &wsConnection{
+		cfg:        h.cfg,
+		conn:       conn,
+		r:          r,
+		protocol:   protocol,
+		ctx:        ctx,
+		cancel:     cancel,
+		operations: make(map[string]context.CancelFunc),
+	}
+	c.serve()
+}
+
+// wsConnection 一个 WebSocket 连接
+type wsConnection struct {
+	cfg      *SubscriptionConfig
+	conn     *websocket.Conn
+	r        *http.Request
+	protocol string
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	writeMu    sync.Mutex
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc // 正在执行的订阅
+	inited     bool                          // 是否已经收到 connection_init
+}
+
+func (c *wsConnection) serve() {
+	defer c.conn.Close()
+	defer c.cancel()
+
+	if c.protocol == ProtocolGraphQLTransportWS {
+		// 规定时间内没有收到 connection_init 时关闭连接
+		timer := time.AfterFunc(wsConnectionInitLimit, func() {
+			c.mu.Lock()
+			inited := c.inited
+			c.mu.Unlock()
+			if !inited {
+				c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+			}
+		})
+		defer timer.Stop()
+	}
+
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			switch err.(type) {
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+				// 消息格式错误
+				if c.protocol == ProtocolGraphQLTransportWS {
+					c.close(wsCloseBadRequest, "Invalid message received")
+				}
+			}
+			return
+		}
+		if !c.handle(&msg) {
+			return
+		}
+	}
+}
+
+// handle 处理收到的消息，返回 false 时关闭连接
+func (c *wsConnection) handle(msg *wsMessage) bool {
+	switch msg.Type {
+	case wsConnectionInit:
+		return c.init(msg.Payload)
+	case wsConnectionTerminate:
+		return false
+	case wsPing:
+		c.send(&wsMessage{Type: wsPong, Payload: msg.Payload})
+	case wsPong:
+	case wsStart, wsSubscribe:
+		return c.subscribe(msg.ID, msg.Payload)
+	case wsStop, wsComplete:
+		c.stop(msg.ID)
+	default:
+		if c.protocol == ProtocolGraphQLTransportWS {
+			c.close(wsCloseBadRequest, "Invalid message type "+msg.Type)
+			return false
+		}
+		c.sendError(msg.ID, errors.New("不支持的消息类型 "+msg.Type))
+	}
+	return true
+}
+
+// init 处理 connection_init，保存 payload 并开始发送心跳
+func (c *wsConnection) init(payload json.RawMessage) bool {
+	c.mu.Lock()
+	inited := c.inited
+	c.inited = true
+	c.mu.Unlock()
+	if inited {
+		if c.protocol == ProtocolGraphQLTransportWS {
+			c.close(wsCloseTooManyInits, "Too many initialisation requests")
+			return false
+		}
+		return true
+	}
+
+	var params map[string]interface{}
+	if len(payload) > 0 && string(payload) != "null" {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			if c.protocol == ProtocolGraphQLTransportWS {
+				c.close(wsCloseBadRequest, "Invalid connection params")
+			} else {
+				c.send(&wsMessage{Type: wsConnectionError, Payload: errorPayload(err)})
+			}
+			return false
+		}
+	}
+	// 订阅都在 connection_init 之后开始，此后 ctx 不再改变
+	c.ctx = context.WithValue(c.ctx, keyOfConnectionParams, params)
+
+	c.send(&wsMessage{Type: wsConnectionAck})
+	go c.keepAlive()
+	return true
+}
+
+// keepAlive 定时发送心跳，graphql-ws 协议发送 ka，graphql-transport-ws 协议发送 ping
+func (c *wsConnection) keepAlive() {
+	typ := wsKeepAlive
+	if c.protocol == ProtocolGraphQLTransportWS {
+		typ = wsPing
+	}
+	if typ == wsKeepAlive {
+		c.send(&wsMessage{Type: typ})
+	}
+	ticker := time.NewTicker(c.cfg.keepAlive())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if c.send(&wsMessage{Type: typ}) != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe 开始执行订阅，返回 false 时关闭连接
+func (c *wsConnection) subscribe(id string, payload json.RawMessage) bool {
+	c.mu.Lock()
+	inited := c.inited
+	c.mu.Unlock()
+	if !inited {
+		if c.protocol == ProtocolGraphQLTransportWS {
+			c.close(wsCloseUnauthorized, "Unauthorized")
+			return false
+		}
+		c.sendError(id, errors.New("没有收到 connection_init 消息"))
+		return true
+	}
+	if id == "" {
+		if c.protocol == ProtocolGraphQLTransportWS {
+			c.close(wsCloseBadRequest, "Missing subscription id")
+			return false
+		}
+		c.sendError(id, errors.New("订阅必须有 id"))
+		return true
+	}
+
+	var opts RequestOptions
+	if err := json.Unmarshal(payload, &opts); err != nil || opts.Query == "" {
+		if c.protocol == ProtocolGraphQLTransportWS {
+			c.close(wsCloseBadRequest, "Invalid subscribe payload")
+			return false
+		}
+		c.sendError(id, errors.New("订阅请求的参数不正确"))
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.mu.Lock()
+	if _, ok := c.operations[id]; ok {
+		c.mu.Unlock()
+		cancel()
+		if c.protocol == ProtocolGraphQLTransportWS {
+			c.close(wsCloseDuplicateID, "Subscriber for "+id+" already exists")
+			return false
+		}
+		c.sendError(id, errors.New("订阅 "+id+" 已经存在"))
+		return true
+	}
+	c.operations[id] = cancel
+	c.mu.Unlock()
+
+	go c.execute(ctx, id, &opts)
+	return true
+}
+
+// execute 执行订阅，发送每个结果，订阅结束时发送 complete
+func (c *wsConnection) execute(ctx context.Context, id string, opts *RequestOptions) {
+	defer c.remove(id, ctx)
+
+	ctx, params := c.cfg.newSubscribeParams(ctx, c.r, opts)
+	dataType := wsData
+	if c.protocol == ProtocolGraphQLTransportWS {
+		dataType = wsNext
+	}
+	for result := range c.cfg.Subscribe(ctx, params) {
+		if ctx.Err() != nil {
+			// 客户端已经取消订阅，不再发送
+			return
+		}
+		result = c.cfg.formatResult(result)
+		if result.HasErrors() && result.Data == nil {
+			// 请求错误，如语法错误、参数错误，订阅结束
+			payload, _ := json.Marshal(result.Errors)
+			c.send(&wsMessage{ID: id, Type: wsError, Payload: payload})
+			return
+		}
+		payload, err := json.Marshal(result)
+		if err != nil {
+			c.sendError(id, err)
+			return
+		}
+		if c.send(&wsMessage{ID: id, Type: dataType, Payload: payload}) != nil {
+			return
+		}
+	}
+	if ctx.Err() == nil {
+		// 订阅函数返回的 channel 已经关闭
+		c.send(&wsMessage{ID: id, Type: wsComplete})
+	}
+}
+
+// stop 客户端停止订阅
+func (c *wsConnection) stop(id string) {
+	c.mu.Lock()
+	cancel, ok := c.operations[id]
+	delete(c.operations, id)
+	c.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// remove 订阅结束，从列表中删除
+func (c *wsConnection) remove(id string, ctx context.Context) {
+	c.mu.Lock()
+	if cancel, ok := c.operations[id]; ok && ctx.Err() == nil {
+		delete(c.operations, id)
+		cancel()
+	}
+	c.mu.Unlock()
+}
+
+// send 发送消息
+func (c *wsConnection) send(msg *wsMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+// sendError 发送订阅错误
+func (c *wsConnection) sendError(id string, err error) {
+	c.send(&wsMessage{ID: id, Type: wsError, Payload: errorPayload(err)})
+}
+
+// close 使用指定的代码关闭连接
+func (c *wsConnection) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second))
+	c.conn.Close()
+}
+
+// errorPayload 错误信息转换为 [{"message": ""}] 形式的 payload
+func errorPayload(err error) json.RawMessage {
+	payload, _ := json.Marshal([]gqlerrors.FormattedError{gqlerrors.FormatError(err)})
+	return payload
+}
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ConvertValue 把 graphql 的输入值转换为 go 类型的值
+// graphql 的 Int 和 Float 可以转换为任意宽度的 go 整数和浮点类型（以及它们的指针），数值溢出时返回错误
+func ConvertValue(val interface{}, typ reflect.Type) (reflect.Value, error) {
+	if typ.Kind() == reflect.Ptr {
+		if val == nil {
+			return reflect.Zero(typ), nil
+		}
+		elem, err := ConvertValue(val, typ.Elem())
+		if err != nil {
+			return elem, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	}
+
+	kind := typ.Kind()
+	if val == nil {
+		if IsNullableKind(kind) {
+			return reflect.Zero(typ), nil
+		}
+		return reflect.Value{}, fmt.Errorf("期望类型：%s, 实际值为空", typ.Name())
+	}
+	src := reflect.ValueOf(val)
+	srcKind := src.Kind()
+
+	if kind == reflect.Map && !src.Type().AssignableTo(typ) {
+		return convertMap(val, typ)
+	}
+	if ary, ok := val.([]interface{}); ok && (kind == reflect.Slice || kind == reflect.Array) {
+		// 列表，包括多维列表和定长数组
+		return convertList(ary, typ)
+	}
+
+	switch {
+	case IsIntType(kind) && (IsIntType(srcKind) || IsFloatType(srcKind)):
+		return convertInteger(src, typ)
+	case IsFloatType(kind) && (IsIntType(srcKind) || IsFloatType(srcKind)):
+		var f float64
+		if IsFloatType(srcKind) {
+			f = src.Float()
+		} else if isUnsigned(srcKind) {
+			f = float64(src.Uint())
+		} else {
+			f = float64(src.Int())
+		}
+		res := reflect.New(typ).Elem()
+		if res.OverflowFloat(f) {
+			return reflect.Value{}, fmt.Errorf("数值 %v 超出 %s 的范围", val, typ.Name())
+		}
+		res.SetFloat(f)
+		return res, nil
+	}
+
+	if src.Type().AssignableTo(typ) {
+		res := reflect.New(typ).Elem()
+		res.Set(src)
+		return res, nil
+	}
+	if srcKind == kind && src.Type().ConvertibleTo(typ) {
+		// 自定义类型，如枚举
+		return src.Convert(typ), nil
+	}
+	return reflect.Value{}, fmt.Errorf("期望类型：%s, 实际类型：%s", typ.Name(), src.Type().Name())
+}
+
+// ParseDefaultValue 把 tag 中的默认值（如 gql:"default=20"）按照 go 类型转换为 graphql 的输入值
+// 支持整数、浮点数、字符串、布尔和时间（RFC3339 格式）类型以及它们的指针
+func ParseDefaultValue(str string, typ reflect.Type) (interface{}, error) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	kind := typ.Kind()
+	switch {
+	case IsTimeType(typ):
+		return time.Parse(time.RFC3339, str)
+	case IsIntType(kind):
+		return strconv.Atoi(str)
+	case IsFloatType(kind):
+		return strconv.ParseFloat(str, 64)
+	case IsBoolType(kind):
+		return strconv.ParseBool(str)
+	case IsStringType(kind):
+		return str, nil
+	}
+	return nil, fmt.Errorf("不支持 %s 类型的默认值", typ.String())
+}
+
+// convertList 转换为切片或数组类型，数组的长度必须与输入的列表长度一致
+func convertList(ary []interface{}, typ reflect.Type) (reflect.Value, error) {
+	var list reflect.Value
+	if typ.Kind() == reflect.Array {
+		if len(ary) != typ.Len() {
+			return reflect.Value{}, fmt.Errorf("长度必须是 %d，实际长度：%d", typ.Len(), len(ary))
+		}
+		list = reflect.New(typ).Elem()
+	} else {
+		list = reflect.MakeSlice(typ, len(ary), len(ary))
+	}
+	for n, a := range ary {
+		item, err := ConvertValue(a, typ.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("第 %d 个元素 %s", n, err.Error())
+		}
+		list.Index(n).Set(item)
+	}
+	return list, nil
+}
+
+// convertMap 转换为 map 类型
+// 输入值可以是 JSON 对象，也可以是 [{key, value}] 形式的列表
+func convertMap(val interface{}, typ reflect.Type) (reflect.Value, error) {
+	res := reflect.MakeMap(typ)
+	set := func(k, v interface{}) error {
+		key, err := convertMapKey(k, typ.Key())
+		if err != nil {
+			return err
+		}
+		elem, err := ConvertValue(v, typ.Elem())
+		if err != nil {
+			return fmt.Errorf("%v: %s", k, err.Error())
+		}
+		res.SetMapIndex(key, elem)
+		return nil
+	}
+
+	switch src := val.(type) {
+	case map[string]interface{}:
+		for k, v := range src {
+			if err := set(k, v); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+	case []interface{}:
+		for _, item := range src {
+			entry, ok := item.(map[string]interface{})
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("期望类型：%s, 元素必须是 {key, value} 对象", typ.String())
+			}
+			if err := set(entry["key"], entry["value"]); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("期望类型：%s, 实际类型：%s", typ.String(), reflect.TypeOf(val).String())
+	}
+	return res, nil
+}
+
+// convertMapKey 转换 map 的 key，JSON 对象的 key 都是字符串，需要转换为数值等类型
+func convertMapKey(key interface{}, typ reflect.Type) (reflect.Value, error) {
+	str, ok := key.(string)
+	if !ok || IsStringType(typ.Kind()) {
+		return ConvertValue(key, typ)
+	}
+	kind := typ.Kind()
+	var parsed interface{}
+	var err error
+	switch {
+	case isUnsigned(kind):
+		parsed, err = strconv.ParseUint(str, 10, 64)
+	case IsIntType(kind):
+		parsed, err = strconv.ParseInt(str, 10, 64)
+	case IsFloatType(kind):
+		parsed, err = strconv.ParseFloat(str, 64)
+	case IsBoolType(kind):
+		parsed, err = strconv.ParseBool(str)
+	default:
+		return ConvertValue(key, typ)
+	}
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("key %s 不能转换为 %s", str, typ.Name())
+	}
+	return ConvertValue(parsed, typ)
+}
+
+// convertInteger 转换为整数类型，检测溢出
+func convertInteger(src reflect.Value, typ reflect.Type) (reflect.Value, error) {
+	res := reflect.New(typ).Elem()
+	srcKind := src.Kind()
+
+	if IsFloatType(srcKind) {
+		f := src.Float()
+		if f != math.Trunc(f) {
+			return reflect.Value{}, fmt.Errorf("数值 %v 不是整数", f)
+		}
+		if isUnsigned(typ.Kind()) {
+			if f < 0 || f >= math.MaxUint64 || res.OverflowUint(uint64(f)) {
+				return reflect.Value{}, fmt.Errorf("数值 %v 超出 %s 的范围", f, typ.Name())
+			}
+			res.SetUint(uint64(f))
+		} else {
+			if f < math.MinInt64 || f >= math.MaxInt64 || res.OverflowInt(int64(f)) {
+				return reflect.Value{}, fmt.Errorf("数值 %v 超出 %s 的范围", f, typ.Name())
+			}
+			res.SetInt(int64(f))
+		}
+		return res, nil
+	}
+
+	if isUnsigned(srcKind) {
+		n := src.Uint()
+		if isUnsigned(typ.Kind()) {
+			if res.OverflowUint(n) {
+				return reflect.Value{}, fmt.Errorf("数值 %d 超出 %s 的范围", n, typ.Name())
+			}
+			res.SetUint(n)
+		} else {
+			if n > math.MaxInt64 || res.OverflowInt(int64(n)) {
+				return reflect.Value{}, fmt.Errorf("数值 %d 超出 %s 的范围", n, typ.Name())
+			}
+			res.SetInt(int64(n))
+		}
+		return res, nil
+	}
+
+	n := src.Int()
+	if isUnsigned(typ.Kind()) {
+		if n < 0 || res.OverflowUint(uint64(n)) {
+			return reflect.Value{}, fmt.Errorf("数值 %d 超出 %s 的范围", n, typ.Name())
+		}
+		res.SetUint(uint64(n))
+	} else {
+		if res.OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("数值 %d 超出 %s 的范围", n, typ.Name())
+		}
+		res.SetInt(n)
+	}
+	return res, nil
+}
+
+func isUnsigned(kind reflect.Kind) bool {
+	return kind == reflect.Uint ||
+		kind == reflect.Uint8 ||
+		kind == reflect.Uint16 ||
+		kind == reflect.Uint32 ||
+		kind == reflect.Uint64 ||
+		kind == reflect.Uintptr
+}
@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEnum int
+
+func TestConvertValueInteger(t *testing.T) {
+	cases := []struct {
+		name string
+		val  interface{}
+		typ  reflect.Type
+		want interface{}
+		err  bool
+	}{
+		{"int to uint8", 255, reflect.TypeOf(uint8(0)), uint8(255), false},
+		{"uint8 overflow", 256, reflect.TypeOf(uint8(0)), nil, true},
+		{"uint8 overflow 300", 300, reflect.TypeOf(uint8(0)), nil, true},
+		{"negative to uint8", -1, reflect.TypeOf(uint8(0)), nil, true},
+		{"negative to uint64", -1, reflect.TypeOf(uint64(0)), nil, true},
+		{"int8 max", 127, reflect.TypeOf(int8(0)), int8(127), false},
+		{"int8 min", -128, reflect.TypeOf(int8(0)), int8(-128), false},
+		{"int8 overflow", 128, reflect.TypeOf(int8(0)), nil, true},
+		{"int8 underflow", -129, reflect.TypeOf(int8(0)), nil, true},
+		{"int16 overflow", math.MaxInt16 + 1, reflect.TypeOf(int16(0)), nil, true},
+		{"int32 overflow", int64(math.MaxInt32) + 1, reflect.TypeOf(int32(0)), nil, true},
+		{"uint32 max", int64(math.MaxUint32), reflect.TypeOf(uint32(0)), uint32(math.MaxUint32), false},
+		{"uint32 overflow", int64(math.MaxUint32) + 1, reflect.TypeOf(uint32(0)), nil, true},
+		{"uint64 max to int64", uint64(math.MaxUint64), reflect.TypeOf(int64(0)), nil, true},
+		{"uint64 to int64", uint64(math.MaxInt64), reflect.TypeOf(int64(0)), int64(math.MaxInt64), false},
+		{"uint64 to uint8 overflow", uint64(256), reflect.TypeOf(uint8(0)), nil, true},
+		{"float to int", 3.0, reflect.TypeOf(0), 3, false},
+		{"float fraction to int", 3.5, reflect.TypeOf(0), nil, true},
+		{"negative float to uint", -1.0, reflect.TypeOf(uint(0)), nil, true},
+		{"float to uint8 overflow", 256.0, reflect.TypeOf(uint8(0)), nil, true},
+		{"float 2^63 to int64", math.Pow(2, 63), reflect.TypeOf(int64(0)), nil, true},
+		{"float -2^63 to int64", -math.Pow(2, 63), reflect.TypeOf(int64(0)), int64(math.MinInt64), false},
+		{"float 2^64 to uint64", math.Pow(2, 64), reflect.TypeOf(uint64(0)), nil, true},
+		{"float NaN to int", math.NaN(), reflect.TypeOf(0), nil, true},
+		{"float Inf to int", math.Inf(1), reflect.TypeOf(0), nil, true},
+		{"int to float32", 3, reflect.TypeOf(float32(0)), float32(3), false},
+		{"float32 overflow", math.MaxFloat64, reflect.TypeOf(float32(0)), nil, true},
+		{"enum", testEnum(2), reflect.TypeOf(testEnum(0)), testEnum(2), false},
+		{"string to int", "1", reflect.TypeOf(0), nil, true},
+	}
+	for _, c := range cases {
+		res, err := ConvertValue(c.val, c.typ)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: 期望错误，实际值 %v", c.name, res)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err.Error())
+			continue
+		}
+		if got := res.Interface(); got != c.want {
+			t.Errorf("%s: 期望 %v(%T)，实际 %v(%T)", c.name, c.want, c.want, got, got)
+		}
+	}
+}
+
+func TestConvertValuePointer(t *testing.T) {
+	res, err := ConvertValue(nil, reflect.TypeOf((*uint8)(nil)))
+	if err != nil || !res.IsNil() {
+		t.Errorf("nil 指针: %v %v", res, err)
+	}
+	res, err = ConvertValue(8, reflect.TypeOf((*uint8)(nil)))
+	if err != nil || *res.Interface().(*uint8) != 8 {
+		t.Errorf("*uint8: %v %v", res, err)
+	}
+	if _, err = ConvertValue(-8, reflect.TypeOf((*uint8)(nil))); err == nil {
+		t.Error("*uint8: 期望错误")
+	}
+	if _, err = ConvertValue(nil, reflect.TypeOf(0)); err == nil {
+		t.Error("nil 转换为 int: 期望错误")
+	}
+	res, err = ConvertValue(nil, reflect.TypeOf([]int{}))
+	if err != nil || !res.IsNil() {
+		t.Errorf("nil 切片: %v %v", res, err)
+	}
+}
+
+func TestConvertValueList(t *testing.T) {
+	res, err := ConvertValue([]interface{}{1, 2}, reflect.TypeOf([]uint8{}))
+	if err != nil || !reflect.DeepEqual(res.Interface(), []uint8{1, 2}) {
+		t.Errorf("[]uint8: %v %v", res, err)
+	}
+	if _, err = ConvertValue([]interface{}{1, 256}, reflect.TypeOf([]uint8{})); err == nil {
+		t.Error("[]uint8 元素溢出: 期望错误")
+	}
+	res, err = ConvertValue([]interface{}{[]interface{}{1}, []interface{}{2, 3}}, reflect.TypeOf([][]int16{}))
+	if err != nil || !reflect.DeepEqual(res.Interface(), [][]int16{{1}, {2, 3}}) {
+		t.Errorf("[][]int16: %v %v", res, err)
+	}
+	res, err = ConvertValue([]interface{}{1, 2, 3}, reflect.TypeOf([3]int{}))
+	if err != nil || res.Interface() != [3]int{1, 2, 3} {
+		t.Errorf("[3]int: %v %v", res, err)
+	}
+	if _, err = ConvertValue([]interface{}{1, 2}, reflect.TypeOf([3]int{})); err == nil {
+		t.Error("[3]int 长度不一致: 期望错误")
+	}
+}
+
+func TestConvertValueMap(t *testing.T) {
+	res, err := ConvertValue(map[string]interface{}{"1": 2}, reflect.TypeOf(map[uint8]int8{}))
+	if err != nil || !reflect.DeepEqual(res.Interface(), map[uint8]int8{1: 2}) {
+		t.Errorf("map[uint8]int8: %v %v", res, err)
+	}
+	if _, err = ConvertValue(map[string]interface{}{"256": 2}, reflect.TypeOf(map[uint8]int8{})); err == nil {
+		t.Error("map key 溢出: 期望错误")
+	}
+	if _, err = ConvertValue(map[string]interface{}{"-1": 2}, reflect.TypeOf(map[uint]int{})); err == nil {
+		t.Error("map key 为负数: 期望错误")
+	}
+	if _, err = ConvertValue(map[string]interface{}{"1": 200}, reflect.TypeOf(map[uint8]int8{})); err == nil {
+		t.Error("map value 溢出: 期望错误")
+	}
+	kv := []interface{}{
+		map[string]interface{}{"key": 1, "value": "a"},
+		map[string]interface{}{"key": 2, "value": "b"},
+	}
+	res, err = ConvertValue(kv, reflect.TypeOf(map[int]string{}))
+	if err != nil || !reflect.DeepEqual(res.Interface(), map[int]string{1: "a", 2: "b"}) {
+		t.Errorf("[{key, value}]: %v %v", res, err)
+	}
+	if _, err = ConvertValue([]interface{}{1}, reflect.TypeOf(map[int]string{})); err == nil {
+		t.Error("[{key, value}] 元素不是对象: 期望错误")
+	}
+}
+
+func TestParseDefaultValue(t *testing.T) {
+	cases := []struct {
+		str  string
+		typ  reflect.Type
+		want interface{}
+		err  bool
+	}{
+		{"20", reflect.TypeOf(uint8(0)), 20, false},
+		{"-1", reflect.TypeOf((*int)(nil)), -1, false},
+		{"1.5", reflect.TypeOf(float32(0)), 1.5, false},
+		{"true", reflect.TypeOf(false), true, false},
+		{"a,b", reflect.TypeOf(""), "a,b", false},
+		{"x", reflect.TypeOf(0), nil, true},
+		{"1", reflect.TypeOf([]int{}), nil, true},
+	}
+	for _, c := range cases {
+		got, err := ParseDefaultValue(c.str, c.typ)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s(%s): 期望错误，实际值 %v", c.str, c.typ, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s(%s): 期望 %v，实际 %v %v", c.str, c.typ, c.want, got, err)
+		}
+	}
+
+	got, err := ParseDefaultValue("2020-01-02T03:04:05Z", reflect.TypeOf(time.Time{}))
+	if err != nil || !got.(time.Time).Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("time: %v %v", got, err)
+	}
+}
@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
@@ -21,7 +20,7 @@ type TypeProp struct {
 
 	IsPrimitive bool // 是否原生类型
 	IsPtr       bool // 是否指针
-	IsList      bool // 是否列表，切片
+	IsList      bool // 是否列表，切片或数组
 
 	// 以下属性来自于对 tag 的解析
 	ValChecker []ValueChecker
@@ -52,22 +51,19 @@ func (t *TypeProp) IsContext() bool {
 func ParseTypeProp(typ reflect.Type) *TypeProp {
 	var p = &TypeProp{SrcType: typ, RealType: typ, Kind: typ.Kind()}
 	isPtr := p.Kind == reflect.Ptr
-	isList := p.Kind == reflect.Slice
+	isList := p.Kind == reflect.Slice || p.Kind == reflect.Array
 	pkg := typ.PkgPath()
 
 	p.PackageName = pkg
 	p.TypeName = typ.Name()
 
 	if isPtr || isList {
-		// 指针或切片
+		// 指针、切片或数组
 		p = ParseTypeProp(typ.Elem())
 		p.SrcType = typ
 		p.IsList = isList
 		p.IsPtr = isPtr
 		// p.PackageName = pkg
-	} else if p.Kind == reflect.Map || p.Kind == reflect.Array {
-		// 映射或数组，不支持
-		panic(errors.New("Do not use map (or array) as params type"))
 	}
 
 	p.IsPrimitive = p.PackageName == ""
@@ -138,7 +134,18 @@ func (f *FuncInfo) FullName() string {
 }
 
 // ParseStructFieldName 解析结构字段名称
+// gql tag 优先于 json tag：gql:"-" 忽略该字段，gql:"name=xxx" 指定名称，都不影响 json 序列化
 func ParseStructFieldName(field *reflect.StructField) string {
+	if field.PkgPath != "" && !field.Anonymous {
+		// 未导出的字段（包括 _）
+		return ""
+	}
+	if HasGqlFlag(field, "-") {
+		return ""
+	}
+	if name := ParseGqlTagValue(field, "name"); name != "" {
+		return name
+	}
 	name := field.Tag.Get("json")
 	if name == "" {
 		if field.Anonymous {
@@ -160,23 +167,125 @@ func ParseStructFieldName(field *reflect.StructField) string {
 	return name
 }
 
+// StructFields 获取结构的字段列表，与 encoding/json 相同，匿名嵌入且 json tag 中没有指定名称（如 json:",inline"）的结构，
+// 其字段提升到当前结构中；嵌入结构在 json tag 中指定名称时，仍作为一个字段
+// 返回字段的 Index 是完整的索引路径，层次浅的字段优先于嵌入结构中的同名字段
+func StructFields(typ reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	positions := map[string]int{}
+	depths := map[string]int{}
+	visiting := map[reflect.Type]bool{}
+
+	var walk func(t reflect.Type, index []int, depth int)
+	walk = func(t reflect.Type, index []int, depth int) {
+		visiting[t] = true
+		defer delete(visiting, t)
+		for n := 0; n < t.NumField(); n++ {
+			field := t.Field(n)
+			field.Index = append(append([]int{}, index...), n)
+			if embedded := inlineStructType(&field); embedded != nil {
+				if !visiting[embedded] {
+					walk(embedded, field.Index, depth+1)
+				}
+				continue
+			}
+			name := ParseStructFieldName(&field)
+			if name == "" {
+				if depth == 0 {
+					// 由调用者处理，如 _ struct{} `gql:"flatten"` 标记
+					fields = append(fields, field)
+				}
+				continue
+			}
+			if d, ok := depths[name]; ok {
+				if d > depth {
+					// 层次更浅的字段覆盖嵌入结构中的同名字段
+					fields[positions[name]] = field
+					depths[name] = depth
+				}
+				continue
+			}
+			positions[name] = len(fields)
+			depths[name] = depth
+			fields = append(fields, field)
+		}
+	}
+	walk(typ, nil, 0)
+	return fields
+}
+
+// inlineStructType 字段需要展开时，返回嵌入的结构类型，否则返回 nil
+func inlineStructType(field *reflect.StructField) reflect.Type {
+	if !field.Anonymous {
+		return nil
+	}
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" {
+		// 指定了名称或者 json:"-"
+		return nil
+	}
+	if HasGqlFlag(field, "-") || ParseGqlTagValue(field, "name") != "" {
+		return nil
+	}
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		if field.PkgPath != "" {
+			// 未导出的结构指针无法创建
+			return nil
+		}
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || IsTimeType(typ) {
+		return nil
+	}
+	return typ
+}
+
+// IsEmbeddedByPointer 字段是否来自于以指针形式嵌入的结构，此类字段的值可能为 nil
+func IsEmbeddedByPointer(typ reflect.Type, index []int) bool {
+	for _, x := range index[:len(index)-1] {
+		typ = typ.Field(x).Type
+		if typ.Kind() == reflect.Ptr {
+			return true
+		}
+	}
+	return false
+}
+
+// HasExplicitName 字段是否在 json 或 gql tag 中指定了名称
+func HasExplicitName(field *reflect.StructField) bool {
+	return strings.Split(field.Tag.Get("json"), ",")[0] != "" || ParseGqlTagValue(field, "name") != ""
+}
+
+// FieldByIndex 根据索引路径获取结构字段，用于赋值，路径中嵌入的结构指针为 nil 时自动创建
+func FieldByIndex(val reflect.Value, index []int) reflect.Value {
+	for n, x := range index {
+		if n > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val
+}
+
 // ParseFieldDesc 解析字段说明
 func ParseFieldDesc(field *reflect.StructField) string {
 	tag := field.Tag.Get("gql")
 	if tag == "" {
 		return ""
 	}
-	items := strings.Split(tag, ",")
-	for _, item := range items {
+	for _, item := range splitGqlTag(tag) {
 		if item == "" {
 			continue
 		}
-		ary := strings.Split(item, "=")
+		ary := strings.SplitN(item, "=", 2)
 		if len(ary) != 2 {
 			continue
 		}
 		key := strings.TrimSpace(ary[0])
-		val := strings.TrimSpace(ary[1])
+		val := unquoteTagValue(ary[1])
 		if key == "" || val == "" {
 			continue
 		}
@@ -189,23 +298,78 @@ func ParseFieldDesc(field *reflect.StructField) string {
 	return ""
 }
 
+// ParseGqlTagValue 解析 gql tag 中 key=value 形式的值，如 gql:"map=kv"
+// 值中有 , 时使用单引号，如 gql:"deprecated='use title, please'"，返回的值不包含单引号
+func ParseGqlTagValue(field *reflect.StructField, key string) string {
+	tag := field.Tag.Get("gql")
+	if tag == "" {
+		return ""
+	}
+	for _, item := range splitGqlTag(tag) {
+		ary := strings.SplitN(item, "=", 2)
+		if len(ary) == 2 && strings.TrimSpace(ary[0]) == key {
+			return unquoteTagValue(ary[1])
+		}
+	}
+	return ""
+}
+
+// HasGqlFlag 判断 gql tag 中是否有某个标记，如 gql:"required"
+func HasGqlFlag(field *reflect.StructField, flag string) bool {
+	tag := field.Tag.Get("gql")
+	if tag == "" {
+		return false
+	}
+	for _, item := range splitGqlTag(tag) {
+		if strings.TrimSpace(item) == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// splitGqlTag 把 gql tag 按 , 分割，单引号中的 , 不作为分隔符
+func splitGqlTag(tag string) []string {
+	var items []string
+	quoted := false
+	start := 0
+	for n, r := range tag {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			items = append(items, tag[start:n])
+			start = n + 1
+		}
+	}
+	return append(items, tag[start:])
+}
+
+// unquoteTagValue 去掉值两边的空白和单引号
+func unquoteTagValue(val string) string {
+	val = strings.TrimSpace(val)
+	if len(val) >= 2 && val[0] == '\'' && val[len(val)-1] == '\'' {
+		return val[1 : len(val)-1]
+	}
+	return val
+}
+
 // ParseValueCheckers 解析数据验证定义
 func ParseValueCheckers(prop *TypeProp, field *reflect.StructField) {
 	tag := field.Tag.Get("gql")
 	if tag == "" {
 		return
 	}
-	items := strings.Split(tag, ",")
-	for _, item := range items {
+	for _, item := range splitGqlTag(tag) {
 		if item == "" {
 			continue
 		}
-		ary := strings.Split(item, "=")
+		ary := strings.SplitN(item, "=", 2)
 		if len(ary) != 2 {
 			continue
 		}
 		key := strings.TrimSpace(ary[0])
-		val := strings.TrimSpace(ary[1])
+		val := unquoteTagValue(ary[1])
 		if key == "" || val == "" {
 			continue
 		}
@@ -8,42 +8,104 @@ import (
 	"github.com/graphql-go/graphql"
 )
 
+// CustomTypeFinder 查找自定义类型（枚举等）对应的 graphql 类型，找不到时返回 nil
+type CustomTypeFinder func(typ reflect.Type) graphql.Output
+
 // StructFieldTypeToGraphType 结构字段类型对应 graphql 类型
-func StructFieldTypeToGraphType(field *reflect.StructField) (grapghType graphql.Output, isStruct bool) {
-	tp := field.Type
-
-	kind := tp.Kind()
-
-	isPtr := kind == reflect.Ptr
-	isList := kind == reflect.Slice
-
-	if isPtr {
-		// 指针、切片、数组
-		// field.Type.Elem()
-		//kind = field.Type.Elem().Kind()
-		tp = field.Type.Elem()
-	} else if isList {
-		tp = field.Type.Elem()
-		if tp.Kind() == reflect.Ptr {
-			tp = tp.Elem()
-		}
-	}
+// 返回的类型已经根据字段的指针、切片结构包装，非指针字段为 NonNull
+// 字段是结构（或接口）时返回 isStruct = true，由调用者生成对象后使用 WrapFieldType 包装
+func StructFieldTypeToGraphType(field *reflect.StructField, finder CustomTypeFinder) (grapghType graphql.Output, isStruct bool) {
+	tp := BaseType(field.Type, finder)
 
-	gType, isStruct, err := TypeToGraphQLType(tp)
+	gType, isStruct, err := TypeToGraphQLType(tp, finder)
 	if err != nil {
 		panic(err)
 	}
 	if isStruct {
 		return nil, true
 	}
-	if isList {
-		return graphql.NewList(gType), false
+	return WrapFieldType(field, gType, finder), false
+}
+
+// BaseType 去掉指针、切片和数组，获取基础类型
+// 自定义类型本身可能是切片，如 []byte，此时不再拆解
+func BaseType(typ reflect.Type, finder CustomTypeFinder) reflect.Type {
+	for {
+		if finder != nil && finder(typ) != nil {
+			return typ
+		}
+		kind := typ.Kind()
+		if kind != reflect.Ptr && kind != reflect.Slice && kind != reflect.Array {
+			return typ
+		}
+		typ = typ.Elem()
 	}
-	return gType, false
+}
+
+// WrapFieldType 根据结构字段的类型包装基础 graphql 类型
+// 带有 gql:"required" 标记的字段强制为 NonNull
+func WrapFieldType(field *reflect.StructField, base graphql.Type, finder CustomTypeFinder) graphql.Output {
+	gType := WrapType(field.Type, BaseType(field.Type, finder), base)
+	if HasGqlFlag(field, "required") {
+		gType = NonNull(gType)
+	}
+	return gType
+}
+
+// WrapType 根据 go 类型的指针、切片、数组结构包装基础 graphql 类型
+// 非指针的值类型为 NonNull，指针、切片、映射、接口可以为空，切片和数组元素按照同样的规则包装
+// 例如 []T 为 [T!]，[]*T 为 [T]，*T 为 T，T 为 T!，[3]T 为 [T!]!，[][]T 为 [[T!]]
+func WrapType(typ reflect.Type, baseType reflect.Type, base graphql.Type) graphql.Output {
+	if typ == baseType {
+		if IsNullableKind(typ.Kind()) {
+			return base
+		}
+		return graphql.NewNonNull(base)
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return Nullable(WrapType(typ.Elem(), baseType, base))
+	case reflect.Slice:
+		return graphql.NewList(WrapType(typ.Elem(), baseType, base))
+	case reflect.Array:
+		// 数组不会为 nil
+		return graphql.NewNonNull(graphql.NewList(WrapType(typ.Elem(), baseType, base)))
+	}
+	return base
+}
+
+// Nullable 去掉 NonNull 包装
+func Nullable(typ graphql.Type) graphql.Output {
+	if nonNull, ok := typ.(*graphql.NonNull); ok {
+		return nonNull.OfType
+	}
+	return typ
+}
+
+// NonNull 包装为 NonNull，已经是 NonNull 时直接返回
+func NonNull(typ graphql.Type) graphql.Output {
+	if nonNull, ok := typ.(*graphql.NonNull); ok {
+		return nonNull
+	}
+	return graphql.NewNonNull(typ)
+}
+
+// IsNullableKind 该类型的值是否可以为 nil
+func IsNullableKind(kind reflect.Kind) bool {
+	return kind == reflect.Ptr ||
+		kind == reflect.Slice ||
+		kind == reflect.Map ||
+		kind == reflect.Interface
 }
 
 // TypeToGraphQLType go 类型转 graphql 类型
-func TypeToGraphQLType(typ reflect.Type) (outType graphql.Output, isStruct bool, err error) {
+// finder 不为 nil 时，优先查找自定义类型
+func TypeToGraphQLType(typ reflect.Type, finder CustomTypeFinder) (outType graphql.Output, isStruct bool, err error) {
+	if finder != nil {
+		if custom := finder(typ); custom != nil {
+			return custom, false, nil
+		}
+	}
 	kind := typ.Kind()
 	if IsIntType(kind) {
 		return graphql.Int, false, nil
@@ -60,11 +122,12 @@ func TypeToGraphQLType(typ reflect.Type) (outType graphql.Output, isStruct bool,
 	if IsTimeType(typ) {
 		return graphql.DateTime, false, nil
 	}
-	if IsStructType(kind) {
+	if IsStructType(kind) || kind == reflect.Interface {
+		// 结构和接口需要生成对象
 		return nil, true, nil
 	}
 
-	return nil, false, fmt.Errorf("不支持 %s 类型", typ.Name())
+	return nil, false, fmt.Errorf("不支持 %s 类型", typ.String())
 }
 
 // IsIntType 是否整数类型